@@ -1,61 +1,90 @@
 package main
 
 import (
+	"encoding/csv"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const sesameAPIURL = "http://cdsweb.u-strasbg.fr/cgi-bin/nph-sesame/-oI/A?"
 
+var reMultiSpace = regexp.MustCompile(`\s+`)
+
+// sesameResult holds everything resolved by Sesame for a single object
+type sesameResult struct {
+	Found            bool
+	ObjectType       string
+	CommonName       string
+	TechnicalOptions []string
+	Aliases          []string
+	RA               float64 // J2000, decimal degrees
+	Dec              float64 // J2000, decimal degrees
+	HasCoords        bool
+}
+
 // querySesame searches for the astronomical object in the CDS Sesame API
-func querySesame(searchInput string) (commonName string, technicalOptions []string) {
+func querySesame(searchInput string) sesameResult {
+	result := sesameResult{ObjectType: "Astronomical Object"}
+
 	targetClean := strings.TrimSpace(searchInput)
 	apiURL := sesameAPIURL + url.QueryEscape(targetClean)
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
-		return "", nil
+		return result
 	}
-	req.Header.Add("User-Agent", "astroquery/0.4.6 (Go-Astro-Session/1.1)")
+	req.Header.Add("User-Agent", "astroquery/0.4.6 (Go-Astro-Session/"+toolVersion+")")
 
 	client := &http.Client{Timeout: 5 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", nil
+		return result
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", nil
+		return result
 	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", nil
+		return result
 	}
 
 	lines := strings.Split(string(bodyBytes), "\n")
-	found := false
-	otype := "Astronomical Object"
 	var allCommonNames []string
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 
 		if strings.HasPrefix(line, "%C.0 ") {
-			otype = strings.TrimPrefix(line, "%C.0 ")
+			result.ObjectType = strings.TrimPrefix(line, "%C.0 ")
+		}
+
+		// "%J 148.8882194 +69.0652947 = 09:55:33.17 +69:03:55.0"
+		if strings.HasPrefix(line, "%J ") && !result.HasCoords {
+			fields := strings.Fields(strings.TrimPrefix(line, "%J "))
+			if len(fields) >= 2 {
+				ra, errRA := strconv.ParseFloat(fields[0], 64)
+				dec, errDec := strconv.ParseFloat(fields[1], 64)
+				if errRA == nil && errDec == nil {
+					result.RA, result.Dec, result.HasCoords = ra, dec, true
+				}
+			}
 		}
 
 		if strings.HasPrefix(line, "%I ") || strings.HasPrefix(line, "%I.0 ") {
-			found = true
+			result.Found = true
 			val := strings.TrimPrefix(line, "%I.0 ")
 			val = strings.TrimPrefix(val, "%I ")
 			valTrim := strings.TrimSpace(val)
+			result.Aliases = append(result.Aliases, reMultiSpace.ReplaceAllString(valTrim, " "))
 
 			if strings.HasPrefix(valTrim, "NAME ") {
 				possibleName := strings.TrimPrefix(valTrim, "NAME ")
@@ -81,14 +110,14 @@ func querySesame(searchInput string) (commonName string, technicalOptions []stri
 						}
 
 						exists := false
-						for _, existOpt := range technicalOptions {
+						for _, existOpt := range result.TechnicalOptions {
 							if existOpt == cleanVal {
 								exists = true
 								break
 							}
 						}
 						if !exists {
-							technicalOptions = append(technicalOptions, cleanVal)
+							result.TechnicalOptions = append(result.TechnicalOptions, cleanVal)
 						}
 					}
 					break
@@ -97,16 +126,16 @@ func querySesame(searchInput string) (commonName string, technicalOptions []stri
 		}
 	}
 
-	commonName = selectBestCommonName(allCommonNames)
+	result.CommonName = selectBestCommonName(allCommonNames)
 
-	if found {
-		fmt.Printf("-> Object found! Type: %s\n", otype)
-		if commonName != "" {
-			fmt.Printf("-> Mapped common name: %s\n", commonName)
+	if result.Found {
+		trPrintf("-> Object found! Type: %s\n", result.ObjectType)
+		if result.CommonName != "" {
+			trPrintf("-> Mapped common name: %s\n", result.CommonName)
 		}
 	}
 
-	return commonName, technicalOptions
+	return result
 }
 
 func selectBestCommonName(names []string) string {
@@ -137,3 +166,77 @@ func selectBestCommonName(names []string) string {
 	}
 	return best
 }
+
+const simbadTAPURL = "https://simbad.cds.unistra.fr/simbad/sim-tap/sync"
+
+// querySimbadCone lists the Messier/NGC/IC objects around a position using the
+// SIMBAD TAP service (ADQL cone search)
+func querySimbadCone(ra, dec, radius float64) ([]coneMatch, error) {
+	query := fmt.Sprintf(`SELECT TOP 100 i.id, b.oid, b.otype_txt, b.galdim_majaxis, b.ra, b.dec,
+DISTANCE(POINT('ICRS', b.ra, b.dec), POINT('ICRS', %[1]f, %[2]f)) AS dist
+FROM basic AS b JOIN ident AS i ON i.oidref = b.oid
+WHERE CONTAINS(POINT('ICRS', b.ra, b.dec), CIRCLE('ICRS', %[1]f, %[2]f, %[3]f)) = 1
+AND (i.id LIKE 'M %%' OR i.id LIKE 'NGC %%' OR i.id LIKE 'IC %%')
+ORDER BY dist`, ra, dec, radius)
+
+	form := url.Values{"REQUEST": {"doQuery"}, "LANG": {"ADQL"}, "FORMAT": {"csv"}, "QUERY": {query}}
+	req, err := http.NewRequest("POST", simbadTAPURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("User-Agent", "astroquery/0.4.6 (Go-Astro-Session/"+toolVersion+")")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SIMBAD returned %s", resp.Status)
+	}
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil
+	}
+
+	// One row per identifier: keep a single designation per object, preferring M > NGC > IC
+	rank := func(id string) int {
+		switch {
+		case strings.HasPrefix(id, "M "):
+			return 0
+		case strings.HasPrefix(id, "NGC "):
+			return 1
+		}
+		return 2
+	}
+	byObject := map[string]int{}
+	var matches []coneMatch
+	for _, r := range records[1:] {
+		if len(r) < 7 {
+			continue
+		}
+		id := reMultiSpace.ReplaceAllString(strings.TrimSpace(r[0]), " ")
+		m := coneMatch{catalogEntry: catalogEntry{Designation: id, ObjectType: r[2]}, Source: "SIMBAD"}
+		m.Size, _ = strconv.ParseFloat(r[3], 64)
+		m.RA, _ = strconv.ParseFloat(r[4], 64)
+		m.Dec, _ = strconv.ParseFloat(r[5], 64)
+		m.Distance, _ = strconv.ParseFloat(r[6], 64)
+
+		if i, ok := byObject[r[1]]; ok {
+			if rank(id) < rank(matches[i].Designation) {
+				matches[i].Designation = id
+			}
+			continue
+		}
+		byObject[r[1]] = len(matches)
+		matches = append(matches, m)
+	}
+	sortConeMatches(matches)
+	return matches, nil
+}
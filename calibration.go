@@ -0,0 +1,340 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Default matching tolerances between lights and calibration masters
+const (
+	defaultTempTolerance     = 2.0 // °C
+	defaultExposureTolerance = 0.5 // seconds
+)
+
+// calibrationConfig holds the matching tolerances from astrosession.json
+type calibrationConfig struct {
+	TempTolerance     float64 `json:"temperature_tolerance_c,omitempty"`
+	ExposureTolerance float64 `json:"exposure_tolerance_s,omitempty"`
+}
+
+// calibSignature is the set of acquisition settings a dark/bias must share with a light.
+// Unknown numeric values are NaN and are not compared.
+type calibSignature struct {
+	Camera   string
+	Gain     float64
+	Offset   float64
+	Temp     float64
+	Exposure float64
+	Binning  float64
+}
+
+// calibFrame is a frame stored in the calibration library
+type calibFrame struct {
+	Path string
+	Type string
+	Sig  calibSignature
+}
+
+func init() {
+	registerCommand("calibration", "calibration ingest [-type dark|bias] <file or folder>",
+		"Move darks/bias into the shared Calibration/ library, sorted by camera, gain, offset, temperature, exposure and binning.",
+		runCalibrationCommand)
+	registerCommand("match", "match [-link] [-temp-tol °C] [-exp-tol s] <Night_ session folder>",
+		"List (or hard-link into the session) the library darks/bias matching the session lights.",
+		runMatchCommand)
+}
+
+// signatureFromHeader reads the calibration-relevant keywords of a frame
+func signatureFromHeader(h fitsHeader) calibSignature {
+	value := func(keys ...string) float64 {
+		for _, k := range keys {
+			if v, ok := h.Float(k); ok {
+				return v
+			}
+		}
+		return math.NaN()
+	}
+	sig := calibSignature{
+		Camera:  strings.TrimSpace(h.String("INSTRUME")),
+		Gain:    value("GAIN", "ISOSPEED"),
+		Offset:  value("OFFSET", "BLKLEVEL"),
+		Temp:    value("CCD-TEMP", "SET-TEMP"),
+		Binning: value("XBINNING", "BINNING"),
+	}
+	sig.Exposure = math.NaN()
+	if exp, ok := exposureTime(h); ok {
+		sig.Exposure = exp
+	}
+	return sig
+}
+
+// libraryDir returns the relative library folder for a frame, e.g.
+// "ZWO ASI2600MM Pro/Darks/G100_O50_-10C_300s_Bin1"
+func (s calibSignature) libraryDir(frameType string) string {
+	camera := s.Camera
+	if camera == "" {
+		camera = "Unknown camera"
+	}
+
+	var parts []string
+	if !math.IsNaN(s.Gain) {
+		parts = append(parts, fmt.Sprintf("G%g", s.Gain))
+	}
+	if !math.IsNaN(s.Offset) {
+		parts = append(parts, fmt.Sprintf("O%g", s.Offset))
+	}
+	if !math.IsNaN(s.Temp) {
+		parts = append(parts, fmt.Sprintf("%.0fC", math.Round(s.Temp)))
+	}
+	if frameType == frameDark && !math.IsNaN(s.Exposure) {
+		parts = append(parts, fmt.Sprintf("%gs", s.Exposure))
+	}
+	if !math.IsNaN(s.Binning) {
+		parts = append(parts, fmt.Sprintf("Bin%g", s.Binning))
+	}
+	settings := strings.Join(parts, "_")
+	if settings == "" {
+		settings = "Unknown"
+	}
+
+	kind := "Darks"
+	if frameType == frameBias {
+		kind = "Bias"
+	}
+	return filepath.Join(safeFolderPart(camera), kind, settings)
+}
+
+// matches reports whether a library frame of frameType can calibrate a light with signature light
+func (s calibSignature) matches(light calibSignature, frameType string, tol calibrationConfig) bool {
+	if s.Camera != "" && light.Camera != "" && !strings.EqualFold(s.Camera, light.Camera) {
+		return false
+	}
+	within := func(a, b, tolerance float64) bool {
+		return math.IsNaN(a) || math.IsNaN(b) || math.Abs(a-b) <= tolerance
+	}
+	if !within(s.Gain, light.Gain, 0) || !within(s.Offset, light.Offset, 0) || !within(s.Binning, light.Binning, 0) {
+		return false
+	}
+	if !within(s.Temp, light.Temp, tol.TempTolerance) {
+		return false
+	}
+	if frameType == frameDark && !within(s.Exposure, light.Exposure, tol.ExposureTolerance) {
+		return false
+	}
+	return true
+}
+
+// describe formats a signature for the console
+func (s calibSignature) describe() string {
+	show := func(v float64, format string) string {
+		if math.IsNaN(v) {
+			return "?"
+		}
+		return fmt.Sprintf(format, v)
+	}
+	return fmt.Sprintf("%s | gain %s | offset %s | %s°C | %ss | bin %s",
+		s.Camera, show(s.Gain, "%g"), show(s.Offset, "%g"), show(s.Temp, "%.1f"), show(s.Exposure, "%g"), show(s.Binning, "%g"))
+}
+
+func runCalibrationCommand(args []string) error {
+	if len(args) == 0 || args[0] != "ingest" {
+		return errors.New("usage: " + commands["calibration"].usage)
+	}
+
+	fs := flag.NewFlagSet("calibration ingest", flag.ExitOnError)
+	forcedType := fs.String("type", "", "treat every frame as 'dark' or 'bias' instead of reading IMAGETYP")
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		return errors.New("usage: " + commands["calibration"].usage)
+	}
+
+	baseDir, _, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+	libraryRoot := filepath.Join(baseDir, calibrationFolder)
+	src := cleanPath(fs.Arg(0))
+
+	files := listFrameFiles(src)
+	if len(files) == 0 {
+		return fmt.Errorf("no frames found in '%s'", src)
+	}
+
+	var jobs []moveJob
+	skipped := 0
+	perFolder := map[string]int{}
+	for _, path := range files {
+		header, err := readFrameHeader(path)
+		if err != nil {
+			trPrintf("  Skipping %s: %v\n", filepath.Base(path), err)
+			skipped++
+			continue
+		}
+		frameType := imageType(header)
+		switch strings.ToLower(*forcedType) {
+		case "dark":
+			frameType = frameDark
+		case "bias":
+			frameType = frameBias
+		}
+		if frameType != frameDark && frameType != frameBias {
+			trPrintf("  Skipping %s: not a dark or bias frame (IMAGETYP '%s')\n", filepath.Base(path), header.String("IMAGETYP"))
+			skipped++
+			continue
+		}
+
+		rel := signatureFromHeader(header).libraryDir(frameType)
+		perFolder[rel]++
+		jobs = append(jobs, moveJob{Src: path, DestDir: filepath.Join(libraryRoot, rel)})
+	}
+
+	if len(jobs) == 0 {
+		return errors.New("no darks or bias frames to ingest")
+	}
+
+	trPrintf("\nIngesting %d frames into %s...\n", len(jobs), libraryRoot)
+	moved := moveWithProgress(jobs)
+
+	folders := make([]string, 0, len(perFolder))
+	for f := range perFolder {
+		folders = append(folders, f)
+	}
+	sort.Strings(folders)
+	for _, f := range folders {
+		fmt.Printf("  📂 %s (%d)\n", filepath.ToSlash(f), perFolder[f])
+	}
+	trPrintf("\n✅ %d frames ingested, %d skipped.\n", len(moved), skipped)
+	return nil
+}
+
+func runMatchCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("match", flag.ExitOnError)
+	link := fs.Bool("link", false, "hard-link the matching frames into <session>/Calibration/Darks and /Bias")
+	tempTol := fs.Float64("temp-tol", cfg.Calibration.TempTolerance, "temperature tolerance in °C")
+	expTol := fs.Float64("exp-tol", cfg.Calibration.ExposureTolerance, "exposure tolerance in seconds")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: " + commands["match"].usage)
+	}
+	tol := calibrationConfig{TempTolerance: *tempTol, ExposureTolerance: *expTol}
+
+	sessionPath := cleanPath(fs.Arg(0))
+
+	// NaN fields make signatures unusable as map keys, group by their description instead
+	type lightGroup struct {
+		sig   calibSignature
+		count int
+	}
+	lightGroups := map[string]*lightGroup{}
+	var groupOrder []string
+	for _, path := range listFrameFiles(filepath.Join(sessionPath, "Lights")) {
+		header, err := readFrameHeader(path)
+		if err != nil {
+			continue
+		}
+		sig := signatureFromHeader(header)
+		if !math.IsNaN(sig.Temp) {
+			sig.Temp = math.Round(sig.Temp) // group lights that only differ by sensor jitter
+		}
+		key := sig.describe()
+		if lightGroups[key] == nil {
+			lightGroups[key] = &lightGroup{sig: sig}
+			groupOrder = append(groupOrder, key)
+		}
+		lightGroups[key].count++
+	}
+	if len(lightGroups) == 0 {
+		return fmt.Errorf("no lights found in '%s'", filepath.Join(sessionPath, "Lights"))
+	}
+
+	libraryRoot := filepath.Join(baseDir, calibrationFolder)
+	library := scanCalibrationLibrary(libraryRoot)
+	trPrintf("Calibration library: %d frames\n", len(library))
+
+	var toLink []calibFrame
+	for _, key := range groupOrder {
+		sig := lightGroups[key].sig
+		trPrintf("\n💡 %d lights: %s\n", lightGroups[key].count, key)
+		for _, frameType := range []string{frameDark, frameBias} {
+			var found []calibFrame
+			for _, f := range library {
+				if f.Type == frameType && f.Sig.matches(sig, frameType, tol) {
+					found = append(found, f)
+				}
+			}
+			if len(found) == 0 {
+				trPrintf("   ⚠️  No matching %s frames\n", strings.ToLower(frameType))
+				continue
+			}
+			trPrintf("   ✅ %d matching %s frames\n", len(found), strings.ToLower(frameType))
+			for _, f := range found {
+				rel, _ := filepath.Rel(baseDir, f.Path)
+				fmt.Printf("      %s\n", filepath.ToSlash(rel))
+			}
+			toLink = append(toLink, found...)
+		}
+	}
+
+	if *link {
+		linked := 0
+		for _, f := range toLink {
+			// Keep the library folders: masters of different signatures often share a file name
+			rel, err := filepath.Rel(libraryRoot, f.Path)
+			if err != nil {
+				return err
+			}
+			dest := filepath.Join(sessionPath, calibrationFolder, rel)
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			if existing, err := os.Stat(dest); err == nil {
+				if source, err := os.Stat(f.Path); err != nil || !os.SameFile(existing, source) {
+					trPrintf("  ⚠️  %s not linked: another file has this name\n", filepath.ToSlash(rel))
+				}
+				continue
+			}
+			if err := os.Link(f.Path, dest); err != nil {
+				if err := os.Symlink(f.Path, dest); err != nil {
+					trPrintf("  Error linking %s: %v\n", filepath.Base(f.Path), err)
+					continue
+				}
+			}
+			linked++
+		}
+		trPrintf("\n🔗 %d frames linked into %s\n", linked, filepath.Join(sessionPath, calibrationFolder))
+	}
+	return nil
+}
+
+// scanCalibrationLibrary reads the headers of every frame in the library
+func scanCalibrationLibrary(libraryRoot string) []calibFrame {
+	var frames []calibFrame
+	for _, path := range listFrameFiles(libraryRoot) {
+		header, err := readFrameHeader(path)
+		if err != nil {
+			continue
+		}
+		frameType := imageType(header)
+		if frameType == "" {
+			// Fall back to the library folder the frame was ingested into
+			if strings.Contains(filepath.ToSlash(path), "/Bias/") {
+				frameType = frameBias
+			} else {
+				frameType = frameDark
+			}
+		}
+		frames = append(frames, calibFrame{Path: path, Type: frameType, Sig: signatureFromHeader(header)})
+	}
+	return frames
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Radius (degrees) of the nearest-object lookups for coordinates
+const coneSearchRadius = 1.0
+
+// Maximum number of nearby objects proposed for a position
+const maxConeMatches = 5
+
+//go:embed dso_catalog.csv
+var dsoCatalogCSV string
+
+// catalogEntry is a deep-sky object of the embedded catalog
+type catalogEntry struct {
+	Designation string // "M 42", "NGC 7000"
+	CommonName  string
+	ObjectType  string
+	RA, Dec     float64 // J2000 degrees
+	Size        float64 // major axis in arcmin
+}
+
+// coneMatch is a catalogued object near a position
+type coneMatch struct {
+	catalogEntry
+	Distance float64 // degrees from the position
+	Source   string  // "SIMBAD" or "catalog"
+}
+
+// inside reports whether the position falls within the object's extent
+func (m coneMatch) inside() bool {
+	return m.Size > 0 && m.Distance*60 <= m.Size/2
+}
+
+// describe prints the match for the proposal list
+func (m coneMatch) describe() string {
+	name := m.Designation
+	if m.CommonName != "" {
+		name += " (" + m.CommonName + ")"
+	}
+	where := tr("%.1f' away", m.Distance*60)
+	if m.inside() {
+		where = tr("in field, %s", where)
+	}
+	if m.ObjectType != "" {
+		return fmt.Sprintf("%s, %s, %s", name, m.ObjectType, where)
+	}
+	return fmt.Sprintf("%s, %s", name, where)
+}
+
+var (
+	dsoCatalog     []catalogEntry
+	dsoCatalogOnce sync.Once
+)
+
+// loadDSOCatalog parses the embedded catalog once
+func loadDSOCatalog() []catalogEntry {
+	dsoCatalogOnce.Do(func() {
+		reader := csv.NewReader(strings.NewReader(dsoCatalogCSV))
+		reader.Comment = '#'
+		records, err := reader.ReadAll()
+		if err != nil {
+			return
+		}
+		for _, r := range records {
+			if len(r) < 6 {
+				continue
+			}
+			raHours, okRA := parseSexagesimal(r[3])
+			dec, okDec := parseSexagesimal(r[4])
+			if !okRA || !okDec {
+				continue
+			}
+			size, _ := strconv.ParseFloat(r[5], 64)
+			dsoCatalog = append(dsoCatalog, catalogEntry{
+				Designation: r[0],
+				CommonName:  r[1],
+				ObjectType:  r[2],
+				RA:          raHours * 15,
+				Dec:         dec,
+				Size:        size,
+			})
+		}
+	})
+	return dsoCatalog
+}
+
+// nearestCatalogObjects searches the embedded catalog around a position
+func nearestCatalogObjects(ra, dec, radius float64) []coneMatch {
+	var matches []coneMatch
+	for _, e := range loadDSOCatalog() {
+		d := angularSeparation(ra, dec, e.RA, e.Dec)
+		// Large objects count when the position falls inside them
+		if d <= radius || d*60 <= e.Size/2 {
+			matches = append(matches, coneMatch{catalogEntry: e, Distance: d, Source: "catalog"})
+		}
+	}
+	sortConeMatches(matches)
+	return matches
+}
+
+// sortConeMatches puts the objects whose extent contains the position first
+// (the one the position is most centered on first), then the closest
+func sortConeMatches(matches []coneMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.inside() != b.inside() {
+			return a.inside()
+		}
+		if a.inside() {
+			return a.Distance/a.Size < b.Distance/b.Size
+		}
+		return a.Distance < b.Distance
+	})
+}
+
+// lookupNearbyObjects proposes catalog designations for a position: SIMBAD cone
+// search when online, the embedded catalog otherwise
+func lookupNearbyObjects(ra, dec float64) []coneMatch {
+	matches, err := querySimbadCone(ra, dec, coneSearchRadius)
+	if err != nil || len(matches) == 0 {
+		if err != nil {
+			fmt.Println(tr("-> SIMBAD is not reachable, using the embedded catalog."))
+		}
+		matches = nearestCatalogObjects(ra, dec, coneSearchRadius)
+	}
+	if len(matches) > maxConeMatches {
+		matches = matches[:maxConeMatches]
+	}
+	return matches
+}
+
+// findCatalogEntry looks up a designation ("M42", "NGC_7000") in the embedded catalog
+func findCatalogEntry(name string) (catalogEntry, bool) {
+	key := normalizeName(name)
+	for _, e := range loadDSOCatalog() {
+		if normalizeName(e.Designation) == key {
+			return e, true
+		}
+	}
+	return catalogEntry{}, false
+}
+
+// Objects already resolved during this run, by normalized name
+var resolvedObjects = map[string]sesameResult{}
+
+// resolveObject queries Sesame for an object, falling back to the embedded
+// catalog when Sesame is unreachable or doesn't know the name. Each name is
+// looked up once per run.
+func resolveObject(name string) sesameResult {
+	key := normalizeName(name)
+	if res, ok := resolvedObjects[key]; ok {
+		return res
+	}
+	res := querySesame(name)
+	if !res.Found {
+		if e, ok := findCatalogEntry(name); ok {
+			trPrintf("-> Found in the embedded catalog: %s\n", e.Designation)
+			res = e.sesameResult()
+		}
+	}
+	resolvedObjects[key] = res
+	return res
+}
+
+// sesameResult turns a catalog entry into the result Sesame would give for it
+func (e catalogEntry) sesameResult() sesameResult {
+	return sesameResult{
+		Found:            true,
+		ObjectType:       e.ObjectType,
+		CommonName:       e.CommonName,
+		TechnicalOptions: []string{formatTargetName(e.Designation)},
+		Aliases:          []string{e.Designation},
+		RA:               e.RA,
+		Dec:              e.Dec,
+		HasCoords:        true,
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// command is a non-interactive subcommand (e.g. "astrosession match <session>")
+type command struct {
+	usage       string
+	description string
+	run         func(args []string) error
+}
+
+var commands = map[string]command{}
+
+// registerCommand adds a subcommand; called from the init of the file implementing it
+func registerCommand(name, usage, description string, run func(args []string) error) {
+	commands[name] = command{usage: usage, description: description, run: run}
+}
+
+// runCommand executes a subcommand and returns the process exit code
+func runCommand(name string, args []string) int {
+	cmd, ok := commands[name]
+	if !ok {
+		if name != "help" {
+			trPrintf("Unknown command '%s'.\n\n", name)
+		}
+		printCommandsUsage()
+		if name == "help" {
+			return 0
+		}
+		return 2
+	}
+	if err := cmd.run(args); err != nil {
+		trPrintf("❌ %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+func printCommandsUsage() {
+	fmt.Println(tr("Usage:"))
+	fmt.Println(tr("  AstroSession-Creator [-equipment name] [-site name] [-panel n]   Interactive session creator"))
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := commands[name]
+		fmt.Printf("  AstroSession-Creator %s\n", c.usage)
+		fmt.Printf("      %s\n", tr(c.description))
+	}
+}
+
+// commandBaseDir resolves the base directory and configuration for a subcommand
+func commandBaseDir() (string, userConfig, error) {
+	baseDir, err := resolveBaseDir()
+	if err != nil {
+		return "", userConfig{}, err
+	}
+	return baseDir, loadUserConfigOrDefault(baseDir), nil
+}
@@ -5,25 +5,31 @@ import (
 	"strings"
 )
 
-// Month abbreviations
-var monthNames = map[int]string{
-	1: "Jan", 2: "Feb", 3: "Mar", 4: "Apr",
-	5: "May", 6: "Jun", 7: "Jul", 8: "Aug",
-	9: "Sep", 10: "Oct", 11: "Nov", 12: "Dec",
-}
+// Version recorded in session metadata and sent to Sesame
+const toolVersion = "1.1"
 
-// Directory structure generated by the script
+// Directory structure generated by the script (capture folders can be changed with capture_folders)
 var captureSubfolders = []string{"Flats", "Lights", "Logs"}
 var processingSubfolders = []string{"PixInsight", "Final"}
 
-// Subfolders created inside the Rejected mirror structure per session
-var rejectedSubfolders = []string{"Lights", "Flats"}
+// Top-level folders next to the targets that are never target folders themselves
+const (
+	rejectedFolder    = "Rejected"
+	calibrationFolder = "Calibration"
+)
+
+// Concurrent file moves used by the bulk move commands
+const moveWorkers = 4
+
+var reCatalogSpace = regexp.MustCompile(`(?i)\b(M|NGC|IC) +(\d+)\b`)
 
 // formatTargetName handles catalogs M, NGC, IC and capitalizes properly
 func formatTargetName(name string) string {
 	catalogs := []string{"M", "NGC", "IC"}
 	reSpaces := regexp.MustCompile(`[-_.]+`)
 	cleanName := reSpaces.ReplaceAllString(name, " ")
+	// "M 81" / "NGC 7000" (as written in FITS OBJECT values) is a single designation
+	cleanName = reCatalogSpace.ReplaceAllString(cleanName, "$1$2")
 
 	words := strings.Fields(cleanName)
 	var formattedWords []string
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// skyPosition is a J2000 position in degrees
+type skyPosition struct {
+	RA, Dec float64
+}
+
+// parseSexagesimal reads "10 55 33.2", "10:55:33.2", "+69 03 55" or a plain
+// decimal number and returns it as a decimal value in the same unit
+func parseSexagesimal(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	sign := 1.0
+	switch s[0] {
+	case '-':
+		sign, s = -1, s[1:]
+	case '+':
+		s = s[1:]
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ':' })
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, false
+	}
+	value := 0.0
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		value += v / math.Pow(60, float64(i))
+	}
+	return sign * value, true
+}
+
+// headerCoordinates returns the pointing of a frame in degrees, from the RA/DEC
+// keywords (degrees) or OBJCTRA/OBJCTDEC (sexagesimal hours and degrees)
+func headerCoordinates(h fitsHeader) (ra, dec float64, ok bool) {
+	ra, okRA := h.Float("RA")
+	dec, okDec := h.Float("DEC")
+	if okRA && okDec {
+		return ra, dec, true
+	}
+	raHours, okRA := parseSexagesimal(h.String("OBJCTRA"))
+	dec, okDec = parseSexagesimal(h.String("OBJCTDEC"))
+	if okRA && okDec {
+		return raHours * 15, dec, true
+	}
+	return 0, 0, false
+}
+
+// framePosition returns where a frame points: its header coordinates, or the
+// OBJECT value when the capture program wrote coordinates there. nil if unknown.
+func framePosition(h fitsHeader, object string) *skyPosition {
+	if ra, dec, ok := headerCoordinates(h); ok {
+		return &skyPosition{RA: ra, Dec: dec}
+	}
+	if ra, dec, ok := parseCoordinateInput(object); ok {
+		return &skyPosition{RA: ra, Dec: dec}
+	}
+	return nil
+}
+
+// angularSeparation returns the distance in degrees between two positions in degrees
+func angularSeparation(ra1, dec1, ra2, dec2 float64) float64 {
+	const rad = math.Pi / 180
+	sinDDec := math.Sin((dec2 - dec1) * rad / 2)
+	sinDRA := math.Sin((ra2 - ra1) * rad / 2)
+	a := sinDDec*sinDDec + math.Cos(dec1*rad)*math.Cos(dec2*rad)*sinDRA*sinDRA
+	return 2 * math.Asin(math.Min(1, math.Sqrt(a))) / rad
+}
+
+// formatCoordinates prints a position as "RA 10h55m33s Dec +69°03'55\""
+func formatCoordinates(ra, dec float64) string {
+	raSec := int(math.Round(math.Mod(ra+360, 360)/15*3600)) % (24 * 3600)
+	sign := "+"
+	if dec < 0 {
+		sign, dec = "-", -dec
+	}
+	decSec := int(math.Round(dec * 3600))
+	return fmt.Sprintf("RA %02dh%02dm%02ds Dec %s%02d°%02d'%02d\"",
+		raSec/3600, raSec/60%60, raSec%60, sign, decSec/3600, decSec/60%60, decSec%60)
+}
+
+var (
+	reCoordinateInput = regexp.MustCompile(`(?i)^[+-]?\d[\d\s+\-.:,hmsd°'"′″]*$`)
+	reCoordinateToken = regexp.MustCompile(`[+-]?\d+(?:\.\d+)?`)
+)
+
+// parseCoordinateInput reads a position typed by the user or written in a FITS
+// OBJECT value: "05 35 17 -05 23 28", "05:35:17.3 -05:23:28", "5h35m17s -5d23m28s"
+// (RA in hours) or "83.82 -5.39" (decimal degrees). It returns degrees.
+func parseCoordinateInput(s string) (ra, dec float64, ok bool) {
+	s = strings.TrimSpace(s)
+	if !reCoordinateInput.MatchString(s) {
+		return 0, 0, false
+	}
+	tokens := reCoordinateToken.FindAllString(s, -1)
+	if len(tokens) < 2 || len(tokens) > 6 {
+		return 0, 0, false
+	}
+
+	// Dec starts at the first signed value, otherwise halfway
+	split := -1
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i][0] == '+' || tokens[i][0] == '-' {
+			split = i
+			break
+		}
+	}
+	if split < 0 {
+		if len(tokens)%2 != 0 {
+			return 0, 0, false
+		}
+		split = len(tokens) / 2
+	}
+	raTokens, decTokens := tokens[:split], tokens[split:]
+	if len(raTokens) > 3 || len(decTokens) > 3 || !sexagesimalParts(raTokens) || !sexagesimalParts(decTokens) {
+		return 0, 0, false
+	}
+
+	ra, okRA := parseSexagesimal(strings.Join(raTokens, ":"))
+	dec, okDec := parseSexagesimal(strings.Join(decTokens, ":"))
+	if !okRA || !okDec {
+		return 0, 0, false
+	}
+	if len(raTokens) > 1 || strings.ContainsAny(s, "hH") {
+		ra *= 15
+	}
+	if ra < 0 || ra >= 360 || dec < -90 || dec > 90 {
+		return 0, 0, false
+	}
+	return ra, dec, true
+}
+
+// sexagesimalParts checks that minutes and seconds are unsigned and below 60
+func sexagesimalParts(tokens []string) bool {
+	for _, t := range tokens[1:] {
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil || v < 0 || v >= 60 || t[0] == '+' || t[0] == '-' {
+			return false
+		}
+	}
+	return true
+}
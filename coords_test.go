@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseCoordinateInput(t *testing.T) {
+	const orionRA, orionDec = (5 + 35/60.0 + 17/3600.0) * 15, -(5 + 23/60.0 + 28/3600.0)
+	tests := []struct {
+		input   string
+		ra, dec float64
+		ok      bool
+	}{
+		{"05 35 17 -05 23 28", orionRA, orionDec, true},
+		{"05:35:17 -05:23:28", orionRA, orionDec, true},
+		{"5h35m17s -5d23m28s", orionRA, orionDec, true},
+		{"05h35m17s -05°23'28\"", orionRA, orionDec, true},
+		{"05:35:17.3 -05:23:28", orionRA + 0.3/3600*15, orionDec, true},
+		{"05 35 17 05 23 28", orionRA, -orionDec, true},
+		{"83.82 -5.39", 83.82, -5.39, true},
+		{"83.82, +5.39", 83.82, 5.39, true},
+		{"10h +69", 150, 69, true},
+		{"05 35 17 -05 23", orionRA, -(5 + 23/60.0), true},
+		{"0 0", 0, 0, true},
+
+		{"M42", 0, 0, false},
+		{"Target 3", 0, 0, false},
+		{"2024-03-01", 0, 0, false},
+		{"05 61 17 -05 23 28", 0, 0, false},
+		{"05 35 17 -05 -23 28", 0, 0, false},
+		{"25h00m00s +10", 0, 0, false},
+		{"-5 10", 0, 0, false},
+		{"10 +95", 0, 0, false},
+		{"1 2 3 4 5 6 7", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			ra, dec, ok := parseCoordinateInput(tt.input)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v (ra %g, dec %g)", ok, tt.ok, ra, dec)
+			}
+			if ok && (math.Abs(ra-tt.ra) > 1e-9 || math.Abs(dec-tt.dec) > 1e-9) {
+				t.Errorf("got %g %g, want %g %g", ra, dec, tt.ra, tt.dec)
+			}
+		})
+	}
+}
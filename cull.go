@@ -0,0 +1,329 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Default statistical rejection: sigmas away from the session median (per filter)
+const defaultCullSigma = 3.0
+
+// Frames needed in a filter group before sigma rejection applies
+const minFramesForSigma = 5
+
+// Lower bound of the robust sigma, relative to the median
+const minRelativeSigma = 0.05
+
+// cullConfig holds the rejection thresholds from astrosession.json. Absolute
+// thresholds set to 0 are disabled.
+type cullConfig struct {
+	MaxHFR          float64  `json:"max_hfr,omitempty"`
+	MaxFWHM         float64  `json:"max_fwhm,omitempty"`
+	MaxEccentricity float64  `json:"max_eccentricity,omitempty"`
+	MinStars        int      `json:"min_stars,omitempty"`
+	MaxBackground   float64  `json:"max_background,omitempty"`
+	Sigma           *float64 `json:"sigma,omitempty"` // nil: defaultCullSigma, 0 disables
+	FlatMinADU      float64  `json:"flat_min_adu,omitempty"`
+	FlatMaxADU      float64  `json:"flat_max_adu,omitempty"`
+}
+
+// sigma returns the sigma threshold, defaultCullSigma when the setting is absent
+func (c cullConfig) sigma() float64 {
+	if c.Sigma == nil {
+		return defaultCullSigma
+	}
+	return *c.Sigma
+}
+
+// analyzedFrame is a frame with its metrics
+type analyzedFrame struct {
+	Path    string
+	Filter  string
+	Quality frameQuality
+	Err     error
+}
+
+func init() {
+	registerCommand("cull", "cull [-dry-run] [-sigma n] <Night_ session folder>",
+		"Measure star count, HFR, FWHM, eccentricity and background of every light/flat and move failing frames into the Rejected mirror.",
+		runCullCommand)
+}
+
+func runCullCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("cull", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report, don't move anything")
+	sigma := fs.Float64("sigma", cfg.Cull.sigma(), "reject frames this many sigmas away from the session median (0 disables)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: " + commands["cull"].usage)
+	}
+	rules := cfg.Cull
+	rules.Sigma = sigma
+
+	sessionPath := cleanPath(fs.Arg(0))
+	lights := analyzeFrames(listFITSFiles(filepath.Join(sessionPath, "Lights")))
+	flats := analyzeFrames(listFITSFiles(filepath.Join(sessionPath, "Flats")))
+	if len(lights) == 0 && len(flats) == 0 {
+		return fmt.Errorf("no FITS lights or flats found in '%s'", sessionPath)
+	}
+
+	var rejected []rejection
+	if len(lights) > 0 {
+		fmt.Println(tr("\nLights:"))
+		fmt.Printf("  %-40s %-6s %6s %6s %6s %6s %9s\n", "File", "Filter", "Stars", "HFR", "FWHM", "Ecc", "Bkg")
+		reasons := cullLights(lights, rules)
+		for _, f := range lights {
+			printFrameQuality(f, reasons[f.Path], false)
+			if len(reasons[f.Path]) > 0 {
+				rejected = append(rejected, rejection{Path: f.Path, Source: "cull", Reason: strings.Join(reasons[f.Path], "; ")})
+			}
+		}
+	}
+	if len(flats) > 0 {
+		fmt.Println(tr("\nFlats:"))
+		fmt.Printf("  %-40s %-6s %6s %6s %6s %6s %9s\n", "File", "Filter", "", "", "", "", "Bkg")
+		reasons := cullFlats(flats, rules)
+		for _, f := range flats {
+			printFrameQuality(f, reasons[f.Path], true)
+			if len(reasons[f.Path]) > 0 {
+				rejected = append(rejected, rejection{Path: f.Path, Source: "cull", Reason: strings.Join(reasons[f.Path], "; ")})
+			}
+		}
+	}
+
+	trPrintf("\n%d of %d frames fail the quality thresholds.\n", len(rejected), len(lights)+len(flats))
+	if *dryRun || len(rejected) == 0 {
+		return nil
+	}
+	return rejectFrames(baseDir, sessionPath, rejected)
+}
+
+// analyzeFrames reads and measures the frames concurrently
+func analyzeFrames(paths []string) []analyzedFrame {
+	frames := make([]analyzedFrame, len(paths))
+	if len(paths) == 0 {
+		return frames
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	queue := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				frames[i].Path = paths[i]
+				img, err := readFITSImage(paths[i])
+				if err != nil {
+					frames[i].Err = err
+				} else {
+					frames[i].Filter = img.Header.String("FILTER")
+					frames[i].Quality = analyzeFrame(img)
+				}
+				mu.Lock()
+				done++
+				trPrintf("\rAnalyzing frames: %d/%d", done, len(paths))
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range paths {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	fmt.Println()
+	return frames
+}
+
+// cullLights applies the absolute and per-filter sigma thresholds to lights
+func cullLights(frames []analyzedFrame, rules cullConfig) map[string][]string {
+	reasons := map[string][]string{}
+	for _, group := range groupByFilter(frames) {
+		stars := groupStats(group, func(q frameQuality) float64 { return float64(q.Stars) })
+		hfr := groupStats(group, func(q frameQuality) float64 { return q.HFR })
+		fwhm := groupStats(group, func(q frameQuality) float64 { return q.FWHM })
+		ecc := groupStats(group, func(q frameQuality) float64 { return q.Eccentricity })
+		bkg := groupStats(group, func(q frameQuality) float64 { return q.Background })
+		useSigma := rules.sigma() > 0 && len(group) >= minFramesForSigma
+
+		for _, f := range group {
+			var r []string
+			q := f.Quality
+			if f.Err != nil {
+				continue // reported by printFrameQuality, never moved
+			}
+			if rules.MinStars > 0 {
+				if q.Stars == 0 {
+					r = append(r, "no stars detected")
+				} else if q.Stars < rules.MinStars {
+					r = append(r, fmt.Sprintf("stars %d < %d", q.Stars, rules.MinStars))
+				}
+			}
+			if q.Stars > 0 {
+				if rules.MaxHFR > 0 && q.HFR > rules.MaxHFR {
+					r = append(r, fmt.Sprintf("HFR %.2f > %.2f", q.HFR, rules.MaxHFR))
+				}
+				if rules.MaxFWHM > 0 && q.FWHM > rules.MaxFWHM {
+					r = append(r, fmt.Sprintf("FWHM %.2f > %.2f", q.FWHM, rules.MaxFWHM))
+				}
+				if rules.MaxEccentricity > 0 && q.Eccentricity > rules.MaxEccentricity {
+					r = append(r, fmt.Sprintf("eccentricity %.2f > %.2f", q.Eccentricity, rules.MaxEccentricity))
+				}
+			}
+			if rules.MaxBackground > 0 && q.Background > rules.MaxBackground {
+				r = append(r, fmt.Sprintf("background %.0f > %.0f", q.Background, rules.MaxBackground))
+			}
+
+			if useSigma {
+				if s := stars.below(float64(q.Stars), rules.sigma()); s != "" {
+					r = append(r, "stars "+s)
+				}
+				if q.Stars > 0 {
+					if s := hfr.above(q.HFR, rules.sigma()); s != "" {
+						r = append(r, "HFR "+s)
+					}
+					if s := fwhm.above(q.FWHM, rules.sigma()); s != "" {
+						r = append(r, "FWHM "+s)
+					}
+					if s := ecc.above(q.Eccentricity, rules.sigma()); s != "" {
+						r = append(r, "eccentricity "+s)
+					}
+				}
+				if s := bkg.above(q.Background, rules.sigma()); s != "" {
+					r = append(r, "background "+s)
+				}
+			}
+			if len(r) > 0 {
+				reasons[f.Path] = r
+			}
+		}
+	}
+	return reasons
+}
+
+// cullFlats rejects flats whose background level is out of range or far from their filter group
+func cullFlats(frames []analyzedFrame, rules cullConfig) map[string][]string {
+	reasons := map[string][]string{}
+	for _, group := range groupByFilter(frames) {
+		bkg := groupStats(group, func(q frameQuality) float64 { return q.Background })
+		useSigma := rules.sigma() > 0 && len(group) >= minFramesForSigma
+
+		for _, f := range group {
+			var r []string
+			level := f.Quality.Background
+			if f.Err != nil {
+				continue // reported by printFrameQuality, never moved
+			}
+			if rules.FlatMinADU > 0 && level < rules.FlatMinADU {
+				r = append(r, fmt.Sprintf("level %.0f < %.0f", level, rules.FlatMinADU))
+			}
+			if rules.FlatMaxADU > 0 && level > rules.FlatMaxADU {
+				r = append(r, fmt.Sprintf("level %.0f > %.0f", level, rules.FlatMaxADU))
+			}
+			if useSigma {
+				if s := bkg.above(level, rules.sigma()); s != "" {
+					r = append(r, "level "+s)
+				} else if s := bkg.below(level, rules.sigma()); s != "" {
+					r = append(r, "level "+s)
+				}
+			}
+			if len(r) > 0 {
+				reasons[f.Path] = r
+			}
+		}
+	}
+	return reasons
+}
+
+// metricStats is the robust center and spread of a metric inside a group
+type metricStats struct {
+	median, sigma float64
+}
+
+func groupStats(group []analyzedFrame, metric func(frameQuality) float64) metricStats {
+	var values []float64
+	for _, f := range group {
+		if f.Err == nil {
+			values = append(values, metric(f.Quality))
+		}
+	}
+	med := median(values)
+	for i := range values {
+		values[i] = math.Abs(values[i] - med)
+	}
+	// Perfectly consistent groups have no spread at all; never go below 5% of the median
+	sigma := math.Max(1.4826*median(values), minRelativeSigma*math.Abs(med))
+	return metricStats{median: med, sigma: sigma}
+}
+
+// above describes v when it is more than k sigmas above the median, "" otherwise
+func (m metricStats) above(v, k float64) string {
+	if m.sigma > 0 && v > m.median+k*m.sigma {
+		return fmt.Sprintf("%.2f > median %.2f + %.1fσ", v, m.median, k)
+	}
+	return ""
+}
+
+// below describes v when it is more than k sigmas below the median, "" otherwise
+func (m metricStats) below(v, k float64) string {
+	if m.sigma > 0 && v < m.median-k*m.sigma {
+		return fmt.Sprintf("%.2f < median %.2f - %.1fσ", v, m.median, k)
+	}
+	return ""
+}
+
+// groupByFilter splits frames by their FILTER keyword, keeping the original order
+func groupByFilter(frames []analyzedFrame) [][]analyzedFrame {
+	index := map[string]int{}
+	var groups [][]analyzedFrame
+	for _, f := range frames {
+		key := strings.ToLower(f.Filter)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], f)
+	}
+	return groups
+}
+
+func printFrameQuality(f analyzedFrame, reasons []string, isFlat bool) {
+	name := filepath.Base(f.Path)
+	if len(name) > 40 {
+		name = name[:37] + "..."
+	}
+	mark := "✅"
+	if len(reasons) > 0 {
+		mark = "❌"
+	}
+	if f.Err != nil {
+		fmt.Printf("%s %-40s %v\n", mark, name, f.Err)
+		return
+	}
+	q := f.Quality
+	if isFlat {
+		fmt.Printf("%s %-40s %-6s %6s %6s %6s %6s %9.0f", mark, name, f.Filter, "", "", "", "", q.Background)
+	} else {
+		fmt.Printf("%s %-40s %-6s %6d %6.2f %6.2f %6.2f %9.0f", mark, name, f.Filter, q.Stars, q.HFR, q.FWHM, q.Eccentricity, q.Background)
+	}
+	if len(reasons) > 0 {
+		fmt.Printf("  %s", strings.Join(reasons, "; "))
+	}
+	fmt.Println()
+}
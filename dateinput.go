@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Oldest year accepted for a session date
+const minSessionYear = 1990
+
+// Month names and abbreviations understood by the date prompt (English,
+// Spanish, French), written without accents
+var inputMonthNames = map[string]time.Month{
+	"january": 1, "february": 2, "march": 3, "april": 4, "may": 5, "june": 6,
+	"july": 7, "august": 8, "september": 9, "october": 10, "november": 11, "december": 12,
+	"enero": 1, "febrero": 2, "marzo": 3, "abril": 4, "mayo": 5, "junio": 6,
+	"julio": 7, "agosto": 8, "septiembre": 9, "setiembre": 9, "octubre": 10, "noviembre": 11, "diciembre": 12,
+	"janvier": 1, "fevrier": 2, "mars": 3, "avril": 4, "mai": 5, "juin": 6,
+	"juillet": 7, "aout": 8, "septembre": 9, "octobre": 10, "novembre": 11, "decembre": 12,
+}
+
+// Relative dates, as days before the current night
+var relativeDates = map[string]int{
+	"today": 0, "tonight": 0, "hoy": 0, "esta noche": 0, "aujourd'hui": 0, "ce soir": 0,
+	"yesterday": 1, "last night": 1, "ayer": 1, "anoche": 1, "hier": 1, "hier soir": 1,
+	"day before yesterday": 2, "anteayer": 2, "antes de ayer": 2, "avant-hier": 2,
+}
+
+var (
+	reDateISO      = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})(?:[t ].*)?$`)
+	reDateCompact  = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})$`)
+	reDateNumeric  = regexp.MustCompile(`^(\d{1,2})[-/.](\d{1,2})(?:[-/.](\d{4}|\d{2}))?$`)
+	reDaysAgo      = regexp.MustCompile(`^(?:hace |il y a )?(\d+) (?:days?|dias?|jours?)(?: ago)?$`)
+	reOrdinal      = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th|er|e|º|o)$`)
+	reDateFillers  = regexp.MustCompile(`\b(?:de|del|of|the|le)\b`)
+	reDateSplitter = regexp.MustCompile(`[\s,]+`)
+)
+
+// parseMonthName returns the month of a name or unambiguous abbreviation of at
+// least three letters ("feb", "febrero", "févr.", "sept")
+func parseMonthName(word string) (time.Month, bool) {
+	word = strings.TrimSuffix(strings.ToLower(transliterate(word)), ".")
+	if len(word) < 3 {
+		return 0, false
+	}
+	var found time.Month
+	for name, m := range inputMonthNames {
+		if strings.HasPrefix(name, word) {
+			if found != 0 && found != m {
+				return 0, false
+			}
+			found = m
+		}
+	}
+	return found, found != 0
+}
+
+// parseSessionDate reads the date typed at the session prompt: empty (tonight),
+// relative ("yesterday", "3 days ago", "ayer", "hier"), ISO ("2025-02-12"),
+// numeric day first ("12/02/2025", "12.02", month first only when the day
+// can't be a month) or with a month name in English, Spanish or French
+// ("12 feb", "Feb 12, 2025", "12 de febrero de 2025", "12 février"). Dates
+// without a year that would be in the future belong to last year. tonight is
+// the current night; later dates and impossible ones (Feb 30) are errors.
+func parseSessionDate(input string, tonight time.Time) (time.Time, error) {
+	s := strings.ToLower(transliterate(strings.Join(strings.Fields(input), " ")))
+	var date time.Time
+	if days, ok := relativeDays(s); ok {
+		date = tonight.AddDate(0, 0, -days)
+	} else {
+		var err error
+		if date, err = parseCalendarDate(s, input, tonight); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if date.After(tonight) {
+		return time.Time{}, errors.New(tr("'%s' is in the future", input))
+	}
+	if date.Year() < minSessionYear {
+		return time.Time{}, errors.New(tr("'%s': the year must be %d or later", input, minSessionYear))
+	}
+	return date, nil
+}
+
+// relativeDays returns how many days before the current night a relative date
+// is (empty and "tonight" are 0)
+func relativeDays(s string) (int, bool) {
+	if s == "" {
+		return 0, true
+	}
+	if days, ok := relativeDates[s]; ok {
+		return days, true
+	}
+	if m := reDaysAgo.FindStringSubmatch(s); m != nil {
+		days, err := strconv.Atoi(m[1])
+		return days, err == nil
+	}
+	return 0, false
+}
+
+// parseCalendarDate reads an ISO, numeric or month name date. A date without a
+// year is the last one that isn't after tonight.
+func parseCalendarDate(s, input string, tonight time.Time) (time.Time, error) {
+	var year, month, day int
+	yearGiven := true
+	switch m := reDateISO.FindStringSubmatch(s); {
+	case m != nil:
+		year, month, day = atoi(m[1]), atoi(m[2]), atoi(m[3])
+	case reDateCompact.MatchString(s):
+		m := reDateCompact.FindStringSubmatch(s)
+		year, month, day = atoi(m[1]), atoi(m[2]), atoi(m[3])
+	case reDateNumeric.MatchString(s):
+		m := reDateNumeric.FindStringSubmatch(s)
+		day, month = atoi(m[1]), atoi(m[2])
+		if month > 12 && day <= 12 {
+			day, month = month, day
+		}
+		yearGiven = m[3] != ""
+		year = fullYear(m[3])
+	default:
+		var ok bool
+		if year, month, day, ok = parseTextDate(s); !ok {
+			return time.Time{}, errors.New(tr("'%s' is not a date I understand", input))
+		}
+		yearGiven = year != 0
+	}
+
+	if !yearGiven {
+		year = tonight.Year()
+	}
+	if month < 1 || month > 12 {
+		return time.Time{}, errors.New(tr("'%s': there is no month %d", input, month))
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, tonight.Location())
+	if day < 1 || date.Day() != day {
+		return time.Time{}, errors.New(tr("'%s': %s %d has no day %d", input, tr(time.Month(month).String()), year, day))
+	}
+	if !yearGiven && date.After(tonight) {
+		date = date.AddDate(-1, 0, 0)
+	}
+	return date, nil
+}
+
+// fullYear turns a typed year into four digits ("25" -> 2025), 0 when empty
+func fullYear(s string) int {
+	if len(s) == 2 {
+		return 2000 + atoi(s)
+	}
+	return atoi(s)
+}
+
+// parseTextDate reads a date written with a month name. year is 0 when not given.
+func parseTextDate(s string) (year, month, day int, ok bool) {
+	s = reDateFillers.ReplaceAllString(s, " ")
+	for _, word := range reDateSplitter.Split(strings.TrimSpace(s), -1) {
+		if word == "" {
+			continue
+		}
+		if m := reOrdinal.FindStringSubmatch(word); m != nil {
+			word = m[1]
+		}
+		if n, convErr := strconv.Atoi(word); convErr == nil {
+			switch {
+			case len(word) == 4 && year == 0:
+				year = n
+			case len(word) <= 2 && day == 0:
+				day = n
+			case len(word) == 2 && year == 0:
+				year = fullYear(word) // "12 feb 25"
+			default:
+				return 0, 0, 0, false
+			}
+			continue
+		}
+		if m, isMonth := parseMonthName(word); isMonth && month == 0 {
+			month = int(m)
+			continue
+		}
+		return 0, 0, 0, false
+	}
+	return year, month, day, month != 0 && day != 0
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// chooseSessionDate asks for the night of the session until it is a valid date
+// and echoes how it was understood. It returns the date and what was typed.
+func chooseSessionDate(reader *bufio.Reader, site *siteProfile) (time.Time, string) {
+	// With a site, before noon we are still in last night's session; without
+	// one the default stays today's date
+	now := time.Now()
+	tonight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if site != nil {
+		tonight = nightDate(now, site.location())
+	}
+
+	fmt.Println("\n----------------------------------------------")
+	fmt.Println(tr("Enter the capture date. Options:"))
+	trPrintf(" [Empty ENTER]  -> Use tonight: %s\n", formatNightDate(tonight))
+	fmt.Println(tr(" 'yesterday'    -> Relative dates, also '3 days ago'"))
+	fmt.Println(tr(" '12 feb'       -> Day and month (this year, or last year if still to come)"))
+	fmt.Println(tr(" '12 feb 2025'  -> Also '2025-02-12', '12/02/2025', '12 febrero', '12 février'"))
+
+	for {
+		fmt.Print(tr("Date: "))
+		input := readInput(reader)
+		date, err := parseSessionDate(input, tonight)
+		if err != nil {
+			trPrintf("❌ %v. Try again.\n", err)
+			continue
+		}
+		trPrintf("-> Night of %s\n", formatNightDate(date))
+		return date, input
+	}
+}
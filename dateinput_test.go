@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSessionDate(t *testing.T) {
+	tonight := time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"", tonight},
+		{"tonight", tonight},
+		{"yesterday", day(2025, time.March, 14)},
+		{"3 days ago", day(2025, time.March, 12)},
+		{"ayer", day(2025, time.March, 14)},
+		{"hace 2 dias", day(2025, time.March, 13)},
+		{"hier", day(2025, time.March, 14)},
+		{"il y a 3 jours", day(2025, time.March, 12)},
+		{"12 feb", day(2025, time.February, 12)},
+		{"12 dec", day(2024, time.December, 12)}, // still to come this year
+		{"Feb 12, 2025", day(2025, time.February, 12)},
+		{"12 feb 2025", day(2025, time.February, 12)},
+		{"12 feb 25", day(2025, time.February, 12)},
+		{"2nd march", day(2025, time.March, 2)},
+		{"2025-02-12", day(2025, time.February, 12)},
+		{"20250212", day(2025, time.February, 12)},
+		{"12/02/2025", day(2025, time.February, 12)},
+		{"12/02/25", day(2025, time.February, 12)},
+		{"12.02", day(2025, time.February, 12)},
+		{"02/13/2025", day(2025, time.February, 13)}, // month first only when the day can't be a month
+		{"12 de febrero de 2025", day(2025, time.February, 12)},
+		{"12 febrero", day(2025, time.February, 12)},
+		{"12 février", day(2025, time.February, 12)},
+		{"1er mars 2024", day(2024, time.March, 1)},
+	}
+	for _, tt := range tests {
+		got, err := parseSessionDate(tt.input, tonight)
+		if err != nil {
+			t.Errorf("parseSessionDate(%q): %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSessionDate(%q) = %s, want %s", tt.input, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+
+	invalid := []string{
+		"30 feb 2024",
+		"2025-13-01",
+		"tomorrow",
+		"2025-03-16",     // future
+		"16 mar 2025",    // future
+		"1 jan 1989",     // before minSessionYear
+		"99999 days ago", // before minSessionYear
+		"99999999999999999999 days ago",
+		"ma",
+	}
+	for _, input := range invalid {
+		if got, err := parseSessionDate(input, tonight); err == nil {
+			t.Errorf("parseSessionDate(%q) = %s, want an error", input, got.Format("2006-01-02"))
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Default maximum length of a target folder name, leaving room for the session
+// path below it within the Windows 260-character limit
+const defaultMaxFolderLength = 80
+
+// Field object orders for the folder name
+const (
+	fieldOrderInput   = "input"   // as typed or found in OBJECT
+	fieldOrderCatalog = "catalog" // M before NGC before IC, then by number
+)
+
+// fieldNamingConfig holds the multi-target folder naming settings from astrosession.json
+type fieldNamingConfig struct {
+	Order           string `json:"order,omitempty"`
+	MaxFolderLength int    `json:"max_folder_length,omitempty"`
+	ASCIIOnly       bool   `json:"ascii_only,omitempty"` // transliterate accents and Greek letters
+}
+
+//go:embed dso_groups.csv
+var dsoGroupsCSV string
+
+// fieldGroup is a set of objects known under a single name
+type fieldGroup struct {
+	Name    string
+	Members []string // normalized designations
+}
+
+var (
+	fieldGroups     []fieldGroup
+	fieldGroupsOnce sync.Once
+)
+
+// loadFieldGroups parses the embedded groups table once
+func loadFieldGroups() []fieldGroup {
+	fieldGroupsOnce.Do(func() {
+		reader := csv.NewReader(strings.NewReader(dsoGroupsCSV))
+		reader.Comment = '#'
+		records, err := reader.ReadAll()
+		if err != nil {
+			return
+		}
+		for _, r := range records {
+			if len(r) < 2 {
+				continue
+			}
+			g := fieldGroup{Name: strings.TrimSpace(r[1])}
+			for _, m := range strings.Fields(r[0]) {
+				g.Members = append(g.Members, normalizeName(formatTargetName(m)))
+			}
+			fieldGroups = append(fieldGroups, g)
+		}
+	})
+	return fieldGroups
+}
+
+// knownAs reports whether an object is the catalog designation member, under
+// its chosen designation or any Sesame alias
+func (o targetObject) knownAs(member string) bool {
+	if normalizeName(o.Designation) == member {
+		return true
+	}
+	for _, a := range o.Aliases {
+		if normalizeName(a) == member {
+			return true
+		}
+	}
+	return false
+}
+
+// findFieldGroup returns the name of the group made of exactly these objects
+func findFieldGroup(objects []targetObject) string {
+	for _, g := range loadFieldGroups() {
+		if len(g.Members) != len(objects) {
+			continue
+		}
+		used := make([]bool, len(objects))
+		matched := 0
+		for _, m := range g.Members {
+			for i, o := range objects {
+				if !used[i] && o.knownAs(m) {
+					used[i] = true
+					matched++
+					break
+				}
+			}
+		}
+		if matched == len(objects) {
+			return g.Name
+		}
+	}
+	return ""
+}
+
+var reCatalogNumber = regexp.MustCompile(`(?i)^(M|NGC|IC)[_ ]*(\d+)`)
+
+// catalogRank returns the sort key of a designation: catalog (M, NGC, IC,
+// then anything else) and number
+func catalogRank(designation string) (int, int) {
+	m := reCatalogNumber.FindStringSubmatch(designation)
+	if m == nil {
+		return 3, 0
+	}
+	n, _ := strconv.Atoi(m[2])
+	switch strings.ToUpper(m[1]) {
+	case "M":
+		return 0, n
+	case "NGC":
+		return 1, n
+	}
+	return 2, n
+}
+
+// sortFieldObjects orders the objects of a field as configured
+func (c fieldNamingConfig) sortFieldObjects(objects []targetObject) {
+	if c.Order != fieldOrderCatalog {
+		return
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		ci, ni := catalogRank(objects[i].Designation)
+		cj, nj := catalogRank(objects[j].Designation)
+		if ci != cj {
+			return ci < cj
+		}
+		if ni != nj {
+			return ni < nj
+		}
+		return objects[i].Designation < objects[j].Designation
+	})
+}
+
+// Plurals of the object kinds that end common names
+var commonNamePlurals = map[string]string{
+	"Galaxy":  "Galaxies",
+	"Nebula":  "Nebulae",
+	"Cluster": "Clusters",
+	"Cloud":   "Clouds",
+	"Remnant": "Remnants",
+}
+
+// joinCommonNames joins the common names of a field: names sharing their last
+// word are merged ("Bode's Galaxy", "Cigar Galaxy" -> "Bode's & Cigar Galaxies"),
+// the rest are listed as they are
+func joinCommonNames(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+	var last string
+	for i, n := range names {
+		words := strings.Fields(n)
+		if len(words) < 2 {
+			last = ""
+			break
+		}
+		if i == 0 {
+			last = words[len(words)-1]
+		} else if words[len(words)-1] != last {
+			last = ""
+			break
+		}
+	}
+	plural, ok := commonNamePlurals[last]
+	if !ok {
+		return strings.Join(names, " & ")
+	}
+	stems := make([]string, len(names))
+	for i, n := range names {
+		stems[i] = strings.TrimSpace(strings.TrimSuffix(n, last))
+	}
+	return strings.Join(stems[:len(stems)-1], ", ") + " & " + stems[len(stems)-1] + " " + plural
+}
+
+// fieldFolderName names the folder of a target from its objects: the technical
+// designations joined with "_" and, in parentheses, the group name from the
+// embedded table or the common names when every object has one, made safe by
+// sanitizeFolderName. Names over the length limit lose the common part, then
+// the trailing designations.
+func (c fieldNamingConfig) fieldFolderName(objects []targetObject) string {
+	techNames := make([]string, len(objects))
+	var commonNames []string
+	for i, o := range objects {
+		techNames[i] = sanitizeFolderName(o.Designation, c.ASCIIOnly)
+		if o.CommonName != "" {
+			commonNames = append(commonNames, o.CommonName)
+		}
+	}
+	tech := strings.Join(techNames, "_")
+
+	common := ""
+	if len(objects) > 1 {
+		common = findFieldGroup(objects)
+	}
+	if common == "" && len(commonNames) == len(objects) && len(objects) > 0 {
+		common = joinCommonNames(commonNames)
+	}
+	if common != "" {
+		common = sanitizeFolderName(common, c.ASCIIOnly)
+	}
+
+	limit := c.MaxFolderLength
+	if limit <= 0 {
+		limit = defaultMaxFolderLength
+	}
+	if common != "" {
+		if name := fmt.Sprintf("%s (%s)", tech, common); utf8.RuneCountInString(name) <= limit {
+			return name
+		}
+	}
+	if utf8.RuneCountInString(tech) <= limit {
+		return tech
+	}
+
+	// Keep as many leading designations as fit, counting the ones left out
+	for n := len(techNames) - 1; n >= 1; n-- {
+		name := fmt.Sprintf("%s_+%d", strings.Join(techNames[:n], "_"), len(techNames)-n)
+		if utf8.RuneCountInString(name) <= limit {
+			return name
+		}
+	}
+	return sanitizeFolderName(string([]rune(tech)[:limit]), false)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// File names written by capture programs with their default settings. A
+// template matches the end of the path without the extension ("/" separates
+// folders); the most specific templates come first.
+var filenamePresets = map[string][]string{
+	// Light_M31_300.0s_Bin1_533MC_gain100_20240301-221530_-10.0C_0001
+	"asiair": {
+		"{type}_{target}_{exposure}s_Bin{bin}_{camera}_{filter}_gain{gain}_{datetime}_{temp}C_{seq}",
+		"{type}_{target}_{exposure}s_Bin{bin}_{camera}_gain{gain}_{datetime}_{temp}C_{seq}",
+		"{type}_{target}_{exposure}s_Bin{bin}_gain{gain}_{datetime}_{temp}C_{seq}",
+	},
+	// M_31_Light_H_Alpha_300_secs_2024-03-01T22-15-30_001
+	"ekos": {
+		"{target}_{type}_{filter}_{exposure}_secs_{datetime}_{seq}",
+		"{target}_{type}_{exposure}_secs_{datetime}_{seq}",
+		"{target}_{type}_{filter}_{exposure}_secs_{seq}",
+		"{target}_{type}_{exposure}_secs_{seq}",
+	},
+	// M31/2024-03-01/LIGHT/2024-03-01_22-15-30_Ha_-10.00_300.00s_0001
+	"nina": {
+		"{target}/{date}/{type}/{datetime}_{filter}_{temp}_{exposure}s_{seq}",
+		"{type}/{datetime}_{filter}_{temp}_{exposure}s_{seq}",
+		"{datetime}_{filter}_{temp}_{exposure}s_{seq}",
+	},
+	// M31_300sec_1x1_Ha_-10C_frame12
+	"sgp": {
+		"{target}_{type}_{exposure}sec_{bin}x{bin}_{filter}_{temp}C_frame{seq}",
+		"{target}_{exposure}sec_{bin}x{bin}_{filter}_{temp}C_frame{seq}",
+		"{target}_{exposure}sec_{bin}x{bin}_{filter}_frame{seq}",
+		"{target}_{exposure}sec_{bin}x{bin}_frame{seq}",
+	},
+	// L_0012_ISO800_300s__-10C (DSLR) or L_0012_Bin1x1_300s__-10C_Ha (CCD)
+	"apt": {
+		"{type}_{seq}_Bin{bin}x{bin}_{exposure}s_{*}_{temp}C_{filter}",
+		"{type}_{seq}_Bin{bin}x{bin}_{exposure}s_{*}_{temp}C",
+		"{type}_{seq}_ISO{iso}_{exposure}s_{*}_{temp}C",
+		"{type}_{seq}_ISO{iso}_{exposure}s",
+	},
+	// 2024-03-01/M31/22_15_30/Light_00001
+	"sharpcap": {
+		"{date}/{target}/{time}/{type}_{seq}",
+		"{date}/{target}/{time}/{seq}",
+	},
+}
+
+// Order in which the presets are tried when filename_patterns is not configured
+var filenamePresetOrder = []string{"asiair", "ekos", "nina", "sgp", "apt", "sharpcap"}
+
+// Pattern of each template token and the header keyword it fills
+var filenameTokens = map[string]struct {
+	pattern string
+	keyword string
+}{
+	"target":   {`.+?`, "OBJECT"},
+	"type":     {`light|lights|flat|flats|dark|darks|bias|biases|offset|darkflat|flatdark|dark_flat|flat_dark|l|f|d|b`, "IMAGETYP"},
+	"filter":   {`[^/]+?`, "FILTER"},
+	"exposure": {`\d+(?:\.\d+)?`, "EXPTIME"},
+	"gain":     {`\d+`, "GAIN"},
+	"iso":      {`\d+`, "ISOSPEED"},
+	"temp":     {`[-+]?\d+(?:\.\d+)?`, "CCD-TEMP"},
+	"bin":      {`\d`, "XBINNING"},
+	"camera":   {`[^_/]+`, "INSTRUME"},
+	"date":     {`\d{4}-?\d{2}-?\d{2}`, ""},
+	"time":     {`\d{2}[-_:]?\d{2}[-_:]?\d{2}`, ""},
+	"datetime": {`\d{4}-?\d{2}-?\d{2}[-_t ]?\d{2}[-_:]?\d{2}[-_:]?\d{2}`, ""},
+	"seq":      {`\d+`, ""},
+	"*":        {`[^/]*?`, ""},
+}
+
+// IMAGETYP written for the frame types found in file names
+var filenameFrameTypes = map[string]string{
+	"l": "Light", "light": "Light", "lights": "Light",
+	"f": "Flat", "flat": "Flat", "flats": "Flat",
+	"d": "Dark", "dark": "Dark", "darks": "Dark",
+	"b": "Bias", "bias": "Bias", "biases": "Bias", "offset": "Bias",
+	"darkflat": "Dark Flat", "flatdark": "Dark Flat", "dark_flat": "Dark Flat", "flat_dark": "Dark Flat",
+}
+
+var (
+	reTemplateToken = regexp.MustCompile(`\{([a-z*]+)\}`)
+	reDateDigits    = regexp.MustCompile(`\d+`)
+)
+
+// filenamePattern is a compiled template
+type filenamePattern struct {
+	Template string
+	Segments int      // path segments the template spans
+	Tokens   []string // token of each capture group
+	re       *regexp.Regexp
+}
+
+// Patterns tried on the frame names, from filename_patterns (all presets by default)
+var filenamePatterns = compileFilenamePatterns(nil)
+
+// applyFilenamePatterns selects the file name patterns of the configuration
+func applyFilenamePatterns(cfg userConfig) {
+	filenamePatterns = compileFilenamePatterns(cfg.FilenamePatterns)
+}
+
+// compileFilenamePatterns compiles the configured entries, each a preset name
+// ("nina") or a template ("{target}_{filter}_{exposure}s_{seq}")
+func compileFilenamePatterns(entries []string) []*filenamePattern {
+	if len(entries) == 0 {
+		entries = filenamePresetOrder
+	}
+	var patterns []*filenamePattern
+	for _, entry := range entries {
+		templates := []string{entry}
+		if !strings.Contains(entry, "{") {
+			preset, ok := filenamePresets[strings.ToLower(strings.TrimSpace(entry))]
+			if !ok {
+				trPrintf("⚠️  Unknown filename pattern preset '%s' (known: %s)\n", entry, strings.Join(filenamePresetOrder, ", "))
+				continue
+			}
+			templates = preset
+		}
+		for _, t := range templates {
+			p, err := compileFilenameTemplate(t)
+			if err != nil {
+				trPrintf("⚠️  Filename pattern '%s': %v\n", t, err)
+				continue
+			}
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// compileFilenameTemplate turns a template into a case-insensitive regular expression
+func compileFilenameTemplate(template string) (*filenamePattern, error) {
+	p := &filenamePattern{Template: template, Segments: strings.Count(template, "/") + 1}
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	last := 0
+	for _, loc := range reTemplateToken.FindAllStringSubmatchIndex(template, -1) {
+		expr.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		name := template[loc[2]:loc[3]]
+		token, ok := filenameTokens[name]
+		if !ok {
+			return nil, errors.New(tr("unknown token {%s}", name))
+		}
+		expr.WriteString("(" + token.pattern + ")")
+		p.Tokens = append(p.Tokens, name)
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(template[last:]) + "$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, err
+	}
+	p.re = re
+	return p, nil
+}
+
+// match returns the token values of a path, the first occurrence of a repeated token winning
+func (p *filenamePattern) match(path string) (map[string]string, bool) {
+	segments := strings.Split(filepath.ToSlash(strings.TrimSuffix(path, filepath.Ext(path))), "/")
+	if len(segments) < p.Segments {
+		return nil, false
+	}
+	m := p.re.FindStringSubmatch(strings.Join(segments[len(segments)-p.Segments:], "/"))
+	if m == nil {
+		return nil, false
+	}
+	values := map[string]string{}
+	for i, name := range p.Tokens {
+		if _, seen := values[name]; !seen {
+			values[name] = m[i+1]
+		}
+	}
+	return values, true
+}
+
+// filenameHeader returns the header keywords the name of a frame carries, from
+// the first pattern that matches it. Capture times go to DATE-LOC: file names
+// use the local time of the capture computer.
+func filenameHeader(path string) fitsHeader {
+	header := fitsHeader{}
+	for _, p := range filenamePatterns {
+		values, ok := p.match(path)
+		if !ok {
+			continue
+		}
+		for name, value := range values {
+			keyword := filenameTokens[name].keyword
+			switch {
+			case name == "type":
+				header[keyword] = filenameFrameTypes[strings.ToLower(value)]
+			case name == "target":
+				header[keyword] = strings.TrimSpace(value)
+			case keyword != "":
+				header[keyword] = value
+			}
+		}
+		if local := filenameDateLoc(values); local != "" {
+			header["DATE-LOC"] = local
+		}
+		break
+	}
+	return header
+}
+
+// filenameDateLoc builds a DATE-LOC value ("2024-03-01T22:15:30", or just the
+// date) from the date and time tokens of a name
+func filenameDateLoc(values map[string]string) string {
+	digits := func(s string) string { return strings.Join(reDateDigits.FindAllString(s, -1), "") }
+	var d string
+	switch {
+	case values["datetime"] != "":
+		d = digits(values["datetime"])
+	case values["date"] != "" && values["time"] != "":
+		d = digits(values["date"]) + digits(values["time"])
+	case values["date"] != "":
+		d = digits(values["date"])
+	}
+	switch len(d) {
+	case 14:
+		return fmt.Sprintf("%s-%s-%sT%s:%s:%s", d[0:4], d[4:6], d[6:8], d[8:10], d[10:12], d[12:14])
+	case 8:
+		return fmt.Sprintf("%s-%s-%s", d[0:4], d[4:6], d[6:8])
+	}
+	return ""
+}
+
+// readFrameHeader reads the header of a frame (FITS keywords, XISF keywords and
+// properties, or the EXIF metadata of camera raws) and fills the keywords it lacks from the file name
+func readFrameHeader(path string) (fitsHeader, error) {
+	header := fitsHeader{}
+	switch {
+	case isFITSFile(path):
+		h, err := readFITSHeader(path)
+		if err != nil {
+			return nil, err
+		}
+		header = h
+	case isXISFFile(path):
+		h, err := readXISFHeader(path)
+		if err != nil {
+			return nil, err
+		}
+		header = h
+	case isEXIFFile(path):
+		h, err := readRawHeader(path)
+		if err != nil {
+			return nil, err
+		}
+		header = h
+	}
+	for key, value := range filenameHeader(path) {
+		if strings.TrimSpace(header.String(key)) == "" && value != "" {
+			header[key] = value
+		}
+	}
+	return header, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilenameHeaderPresets(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want fitsHeader
+	}{
+		{
+			name: "asiair",
+			path: "Light_M31_300.0s_Bin1_533MC_gain100_20240301-221530_-10.0C_0001.fit",
+			want: fitsHeader{"IMAGETYP": "Light", "OBJECT": "M31", "EXPTIME": "300.0", "XBINNING": "1", "INSTRUME": "533MC",
+				"GAIN": "100", "CCD-TEMP": "-10.0", "DATE-LOC": "2024-03-01T22:15:30"},
+		},
+		{
+			name: "ekos",
+			path: "M_31_Light_H_Alpha_300_secs_2024-03-01T22-15-30_001.fits",
+			want: fitsHeader{"OBJECT": "M_31", "IMAGETYP": "Light", "FILTER": "H_Alpha", "EXPTIME": "300", "DATE-LOC": "2024-03-01T22:15:30"},
+		},
+		{
+			name: "nina with folders",
+			path: "D:/Astro/M31/2024-03-01/LIGHT/2024-03-01_22-15-30_Ha_-10.00_300.00s_0001.fits",
+			want: fitsHeader{"OBJECT": "M31", "IMAGETYP": "Light", "FILTER": "Ha", "CCD-TEMP": "-10.00", "EXPTIME": "300.00", "DATE-LOC": "2024-03-01T22:15:30"},
+		},
+		{
+			name: "sgp",
+			path: "M31_300sec_1x1_Ha_-10C_frame12.fit",
+			want: fitsHeader{"OBJECT": "M31", "EXPTIME": "300", "XBINNING": "1", "FILTER": "Ha", "CCD-TEMP": "-10"},
+		},
+		{
+			name: "apt dslr",
+			path: "L_0012_ISO800_300s__-10C.CR2",
+			want: fitsHeader{"IMAGETYP": "Light", "ISOSPEED": "800", "EXPTIME": "300", "CCD-TEMP": "-10"},
+		},
+		{
+			name: "sharpcap",
+			path: "2024-03-01/M31/22_15_30/Light_00001.fits",
+			want: fitsHeader{"OBJECT": "M31", "IMAGETYP": "Light", "DATE-LOC": "2024-03-01T22:15:30"},
+		},
+		{
+			name: "unknown name",
+			path: "IMG_1234.CR2",
+			want: fitsHeader{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filenameHeader(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		want     map[string]string // nil: no match
+	}{
+		{"{target}-{filter}-{exposure}s-{seq}", "NGC7000-OIII-600s-12.fits", map[string]string{"target": "NGC7000", "filter": "OIII", "exposure": "600", "seq": "12"}},
+		{"{target}-{filter}-{exposure}s-{seq}", "NGC7000-OIII-600-12.fits", nil},
+		{"{type}_{seq}", "FLAT_003.fits", map[string]string{"type": "FLAT", "seq": "003"}},
+		{"{target}/{type}/{*}_{seq}", "Archive/M42/Flats/anything here_07.fit", map[string]string{"target": "M42", "type": "Flats", "*": "anything here", "seq": "07"}},
+		{"{target}/{type}/{*}_{seq}", "Flats/x_07.fit", nil},
+		{"{target}_{seq}_{seq}", "M42_1_2.fit", map[string]string{"target": "M42", "seq": "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template+" "+tt.path, func(t *testing.T) {
+			p, err := compileFilenameTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := p.match(tt.path)
+			if ok != (tt.want != nil) || (ok && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("got %v (%v), want %v", got, ok, tt.want)
+			}
+		})
+	}
+
+	if _, err := compileFilenameTemplate("{target}_{foo}"); err == nil {
+		t.Error("unknown token accepted")
+	}
+}
+
+func TestCompileFilenamePatterns(t *testing.T) {
+	got := compileFilenamePatterns([]string{"NINA", "unknown", "{target}_{seq}", "{bad}"})
+	var templates []string
+	for _, p := range got {
+		templates = append(templates, p.Template)
+	}
+	want := append(append([]string{}, filenamePresets["nina"]...), "{target}_{seq}")
+	if !reflect.DeepEqual(templates, want) {
+		t.Errorf("got %q, want %q", templates, want)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -88,7 +90,24 @@ func printProgressBar(totalBytes *int64, movedBytes *int64, done chan bool) {
 	}
 }
 
-func moveCrossDevice(src, dst string, movedBytes *int64) error {
+// moveLog collects the files moved by concurrent moveFiles calls
+type moveLog struct {
+	mu    sync.Mutex
+	files []movedFile
+}
+
+func (l *moveLog) add(f movedFile) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.files = append(l.files, f)
+}
+
+// moveCrossDevice moves src to dst. It returns the SHA-256 of a file copied from
+// another disk, computed while it is copied; a renamed file isn't read again.
+func moveCrossDevice(src, dst string, movedBytes *int64) (string, error) {
 	info, err := os.Stat(src)
 	fileSize := int64(0)
 	if err == nil {
@@ -100,36 +119,38 @@ func moveCrossDevice(src, dst string, movedBytes *int64) error {
 		if strings.Contains(strings.ToLower(err.Error()), "cross-device") || strings.Contains(strings.ToLower(err.Error()), "invalid cross-device") || runtime.GOOS == "windows" {
 			return copyAndDelete(src, dst, movedBytes)
 		}
-		return err
+		return "", err
 	}
 
 	// If Rename worked quickly (same device), append bytes at once
 	if movedBytes != nil && fileSize > 0 {
 		atomic.AddInt64(movedBytes, fileSize)
 	}
-	return nil
+	return "", nil
 }
 
-func copyAndDelete(src, dst string, movedBytes *int64) error {
+func copyAndDelete(src, dst string, movedBytes *int64) (string, error) {
 	in, err := os.Open(src)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer in.Close()
 
 	out, err := os.Create(dst)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer out.Close()
+	defer out.Close() // on errors; a second Close after the explicit one is harmless
 
-	buf := make([]byte, 32*1024) // 32KB buffer (tamaño estándar en go io.Copy)
+	hasher := sha256.New()
+	buf := make([]byte, 32*1024) // 32KB buffer (standard io.Copy size)
 	for {
 		n, err := in.Read(buf)
 		if n > 0 {
 			if _, werr := out.Write(buf[:n]); werr != nil {
-				return werr
+				return "", werr
 			}
+			hasher.Write(buf[:n])
 			if movedBytes != nil {
 				atomic.AddInt64(movedBytes, int64(n))
 			}
@@ -138,12 +159,20 @@ func copyAndDelete(src, dst string, movedBytes *int64) error {
 			break
 		}
 		if err != nil {
-			return err
+			return "", err
 		}
 	}
 
+	// The source goes away only once the copy is known to be on the destination disk
+	if err := out.Sync(); err != nil {
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
 	in.Close() // Close source before removing it
-	return os.Remove(src)
+	return hex.EncodeToString(hasher.Sum(nil)), os.Remove(src)
 }
 
 func checkDuplicates(srcClean, destDir string) bool {
@@ -172,59 +201,195 @@ func checkDuplicates(srcClean, destDir string) bool {
 	return false
 }
 
-func getUniqueDestPath(destPath string) string {
-	if _, err := os.Stat(destPath); os.IsNotExist(err) {
-		return destPath
-	}
-
+// reserveDestPath claims a free name for destPath, appending _1, _2... when it
+// is taken. The name is created empty with O_EXCL so that concurrent moves into
+// the same folder never pick the same file; the move then replaces it.
+func reserveDestPath(destPath string) (string, error) {
 	ext := filepath.Ext(destPath)
 	base := strings.TrimSuffix(destPath, ext)
-	counter := 1
+	path := destPath
+	for counter := 1; ; counter++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return path, f.Close()
+		}
+		if !os.IsExist(err) {
+			return "", err
+		}
+		path = fmt.Sprintf("%s_%d%s", base, counter, ext)
+	}
+}
 
-	for {
-		newPath := fmt.Sprintf("%s_%d%s", base, counter, ext)
-		if _, err := os.Stat(newPath); os.IsNotExist(err) {
-			return newPath
+// removeEmptyFolders removes root and the folders below it when they hold no
+// files, then its parents that became empty up to (not including) stop
+func removeEmptyFolders(root, stop string) {
+	if entries, err := os.ReadDir(root); err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				removeEmptyFolders(filepath.Join(root, e.Name()), root)
+			}
+		}
+	}
+	// os.Remove fails on folders that are not empty
+	for dir := root; dir != stop && strings.HasPrefix(dir, stop); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
 		}
-		counter++
 	}
 }
 
-func moveFiles(srcClean, destDir string, wg *sync.WaitGroup, movedBytes *int64) {
+// moveFiles moves the files of a folder (or a single file) into destDir. With
+// perFilter, FITS frames go into a subfolder named after their FILTER keyword.
+func moveFiles(srcClean, destDir string, perFilter bool, wg *sync.WaitGroup, movedBytes *int64, log *moveLog) {
 	defer wg.Done()
 
 	info, err := os.Stat(srcClean)
 	if err != nil {
-		fmt.Printf("❌ Error reading source '%s': %v\n", srcClean, err)
+		trPrintf("❌ Error reading source '%s': %v\n", srcClean, err)
 		return
 	}
 
 	count := 0
-	if info.IsDir() {
-		entries, err := os.ReadDir(srcClean)
-		if err != nil {
-			fmt.Printf("❌ Error reading %s: %v\n", srcClean, err)
-			return
+	for _, srcPath := range sourceFiles(srcClean, info) {
+		if moveOneFile(srcPath, frameDestDir(srcPath, destDir, perFilter), movedBytes, log) {
+			count++
 		}
-		for _, e := range entries {
-			if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
-				srcPath := filepath.Join(srcClean, e.Name())
-				destPath := getUniqueDestPath(filepath.Join(destDir, e.Name()))
+	}
+	trPrintf("\n✅ %d files successfully moved to -> %s\n", count, filepath.Base(destDir))
+}
 
-				if err := moveCrossDevice(srcPath, destPath, movedBytes); err != nil {
-					fmt.Printf("\n  Error moving %s: %v\n", e.Name(), err)
-				} else {
-					count++
-				}
-			}
+// sourceFiles returns the files moveFiles moves from a dragged source: the
+// visible files at the top of a folder (not its subfolders), or the file itself
+func sourceFiles(srcClean string, info os.FileInfo) []string {
+	if !info.IsDir() {
+		return []string{srcClean}
+	}
+	entries, err := os.ReadDir(srcClean)
+	if err != nil {
+		trPrintf("❌ Error reading %s: %v\n", srcClean, err)
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			files = append(files, filepath.Join(srcClean, e.Name()))
 		}
-	} else {
-		destPath := getUniqueDestPath(filepath.Join(destDir, filepath.Base(srcClean)))
-		if err := moveCrossDevice(srcClean, destPath, movedBytes); err != nil {
-			fmt.Printf("\n  Error moving %s: %v\n", info.Name(), err)
-		} else {
-			count++
+	}
+	return files
+}
+
+// frameFilesToMove returns the frames among the files moveFiles would move from src
+func frameFilesToMove(src string, match func(name string) bool) []string {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil
+	}
+	var frames []string
+	for _, f := range sourceFiles(src, info) {
+		if match(f) {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+// frameDestDir returns the filter subfolder of destDir for a frame when
+// perFilter is set (creating it), or destDir itself
+func frameDestDir(srcPath, destDir string, perFilter bool) string {
+	if !perFilter || !isFrameFile(srcPath) {
+		return destDir
+	}
+	header, err := readFrameHeader(srcPath)
+	if err != nil {
+		return destDir
+	}
+	filter := safeFolderPart(header.String("FILTER"))
+	if filter == "" {
+		return destDir
+	}
+	dir := filepath.Join(destDir, filter)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return destDir
+	}
+	return dir
+}
+
+// moveOneFile moves a single file into destDir without overwriting and records it in log
+func moveOneFile(srcPath, destDir string, movedBytes *int64, log *moveLog) bool {
+	destPath, err := reserveDestPath(filepath.Join(destDir, filepath.Base(srcPath)))
+	if err != nil {
+		trPrintf("\n  Error moving %s: %v\n", filepath.Base(srcPath), err)
+		return false
+	}
+
+	var size int64
+	if info, err := os.Stat(srcPath); err == nil {
+		size = info.Size()
+	}
+
+	sum, err := moveCrossDevice(srcPath, destPath, movedBytes)
+	if err != nil {
+		if _, statErr := os.Stat(srcPath); statErr == nil {
+			os.Remove(destPath) // the reserved name, the frame is still at its source
 		}
+		trPrintf("\n  Error moving %s: %v\n", filepath.Base(srcPath), err)
+		return false
+	}
+
+	log.add(movedFile{
+		Path:    destPath,
+		Source:  srcPath,
+		Bytes:   size,
+		SHA256:  sum,
+		MovedAt: time.Now(),
+	})
+	return true
+}
+
+// moveJob is a single file to move into a destination folder
+type moveJob struct {
+	Src     string
+	DestDir string
+}
+
+// moveWithProgress runs the jobs on a few workers with the progress bar and
+// returns the files that were moved
+func moveWithProgress(jobs []moveJob) []movedFile {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	var totalBytes, movedBytes int64
+	for _, j := range jobs {
+		totalBytes += calculateTotalSize(j.Src)
+	}
+
+	var log moveLog
+	var wg sync.WaitGroup
+	queue := make(chan moveJob)
+	doneChan := make(chan bool)
+	go printProgressBar(&totalBytes, &movedBytes, doneChan)
+
+	for w := 0; w < moveWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				if err := os.MkdirAll(j.DestDir, 0755); err != nil {
+					trPrintf("\n  Error creating %s: %v\n", j.DestDir, err)
+					continue
+				}
+				moveOneFile(j.Src, j.DestDir, &movedBytes, &log)
+			}
+		}()
 	}
-	fmt.Printf("\n✅ %d files successfully moved to -> %s\n", count, filepath.Base(destDir))
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+
+	wg.Wait()
+	doneChan <- true
+	fmt.Printf("\rProgress: [==================================================] 100%% | ETA: 0s          \n")
+	return log.files
 }
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FITS files are organized in 2880-byte blocks of 80-character cards
+const (
+	fitsBlockSize = 2880
+	fitsCardSize  = 80
+)
+
+// Extensions treated as FITS frames
+var fitsExtensions = []string{".fits", ".fit", ".fts"}
+
+// Other frame formats: camera raws and XISF, read by their own readers, and
+// TIFF/PNG previews, described by their file name
+var otherFrameExtensions = []string{".cr2", ".cr3", ".nef", ".arw", ".dng", ".orf", ".raf", ".rw2", ".pef", ".tif", ".tiff", ".png", ".xisf"}
+
+// fitsHeader holds the keyword values of a FITS primary header (quotes already removed)
+type fitsHeader map[string]string
+
+// isFITSFile reports whether the file name has a FITS extension
+func isFITSFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range fitsExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// readFITSHeader reads the primary header of a FITS file
+func readFITSHeader(path string) (fitsHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, _, err := parseFITSHeader(f)
+	return header, err
+}
+
+// parseFITSHeader reads header blocks until the END card and returns the header
+// together with its size in bytes (the offset where the data unit starts)
+func parseFITSHeader(r io.Reader) (fitsHeader, int64, error) {
+	header := fitsHeader{}
+	block := make([]byte, fitsBlockSize)
+	var size int64
+
+	for {
+		if _, err := io.ReadFull(r, block); err != nil {
+			if size == 0 {
+				return nil, 0, errors.New("not a FITS file")
+			}
+			return nil, 0, fmt.Errorf("truncated FITS header: %w", err)
+		}
+		if size == 0 && !bytes.HasPrefix(block, []byte("SIMPLE  =")) {
+			return nil, 0, errors.New("not a FITS file")
+		}
+		size += fitsBlockSize
+
+		for i := 0; i < fitsBlockSize; i += fitsCardSize {
+			card := string(block[i : i+fitsCardSize])
+			key := strings.TrimSpace(card[:8])
+			if key == "END" {
+				return header, size, nil
+			}
+			if card[8:10] != "= " {
+				continue // COMMENT, HISTORY and blank cards
+			}
+			if _, exists := header[key]; !exists {
+				header[key] = parseFITSValue(card[10:])
+			}
+		}
+	}
+}
+
+// parseFITSValue extracts the value of a card, removing quotes and the trailing comment
+func parseFITSValue(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "'") {
+		var sb strings.Builder
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '\'' {
+				if i+1 < len(raw) && raw[i+1] == '\'' {
+					sb.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			sb.WriteByte(raw[i])
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+	if idx := strings.Index(raw, "/"); idx >= 0 {
+		raw = raw[:idx]
+	}
+	return strings.TrimSpace(raw)
+}
+
+// String returns the value of a keyword or "" when missing
+func (h fitsHeader) String(key string) string {
+	return h[key]
+}
+
+// Float returns the numeric value of a keyword
+func (h fitsHeader) Float(key string) (float64, bool) {
+	v, ok := h[key]
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.Replace(v, "D", "E", 1), 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// Int returns the integer value of a keyword
+func (h fitsHeader) Int(key string) (int, bool) {
+	f, ok := h.Float(key)
+	return int(f), ok
+}
+
+// firstFrameHeader returns the header of src when it is a frame, or of the
+// first frame found directly inside src when it is a folder
+func firstFrameHeader(src string) (fitsHeader, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return readFrameHeader(src)
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() && !strings.HasPrefix(e.Name(), ".") && isFrameFile(e.Name()) {
+			return readFrameHeader(filepath.Join(src, e.Name()))
+		}
+	}
+	return nil, errors.New("no frames found")
+}
+
+// Frame types normalized from IMAGETYP
+const (
+	frameLight    = "Light"
+	frameFlat     = "Flat"
+	frameDark     = "Dark"
+	frameBias     = "Bias"
+	frameDarkFlat = "DarkFlat"
+)
+
+// imageType normalizes IMAGETYP/FRAME values ("Light Frame", "MASTERDARK", "Bias Frame"...)
+func imageType(header fitsHeader) string {
+	value := strings.ToUpper(header.String("IMAGETYP"))
+	if value == "" {
+		value = strings.ToUpper(header.String("FRAME"))
+	}
+	switch {
+	case strings.Contains(value, "FLAT") && strings.Contains(value, "DARK"):
+		return frameDarkFlat
+	case strings.Contains(value, "FLAT"):
+		return frameFlat
+	case strings.Contains(value, "DARK"):
+		return frameDark
+	case strings.Contains(value, "BIAS") || strings.Contains(value, "ZERO"):
+		return frameBias
+	case strings.Contains(value, "LIGHT") || strings.Contains(value, "OBJECT"):
+		return frameLight
+	}
+	return ""
+}
+
+// exposureTime returns EXPTIME (or EXPOSURE) in seconds
+func exposureTime(header fitsHeader) (float64, bool) {
+	if v, ok := header.Float("EXPTIME"); ok {
+		return v, true
+	}
+	return header.Float("EXPOSURE")
+}
+
+// isFrameFile reports whether the file name is a FITS or another supported frame format
+func isFrameFile(name string) bool {
+	if isFITSFile(name) {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range otherFrameExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// listFITSFiles returns the FITS files under root (recursively), skipping hidden entries
+func listFITSFiles(root string) []string {
+	return listFiles(root, isFITSFile)
+}
+
+// listFrameFiles returns the frames of any supported format under root (recursively)
+func listFrameFiles(root string) []string {
+	return listFiles(root, isFrameFile)
+}
+
+// listFiles returns the files under root whose name is accepted by match, skipping hidden entries
+func listFiles(root string, match func(name string) bool) []string {
+	var files []string
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && match(d.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+// dateObs returns the exposure start from DATE-OBS (UTC)
+func dateObs(header fitsHeader) (time.Time, bool) {
+	value := header.String("DATE-OBS")
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSuffix(value, "Z"), time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dateLoc returns the local capture time from DATE-LOC in loc. dateOnly is set
+// when it holds just a date, which file names use for the night itself.
+func dateLoc(header fitsHeader, loc *time.Location) (t time.Time, dateOnly bool, ok bool) {
+	value := header.String("DATE-LOC")
+	if value == "" {
+		return time.Time{}, false, false
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, true
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, true
+	}
+	return time.Time{}, false, false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Images are binned while reading until their largest side is at most this many pixels
+const maxAnalysisSize = 3000
+
+// fitsImage is the first plane of a FITS primary image, binned for analysis
+type fitsImage struct {
+	Width  int
+	Height int
+	Bin    int // pixels of the original image per analysis pixel (per axis)
+	Data   []float32
+	Header fitsHeader
+}
+
+// at returns the pixel value at x, y
+func (img *fitsImage) at(x, y int) float32 {
+	return img.Data[y*img.Width+x]
+}
+
+// readFITSImage reads the primary image of a FITS file. One-shot color (BAYERPAT)
+// frames are binned at least 2x2 so each analysis pixel covers a full Bayer cell.
+func readFITSImage(path string) (*fitsImage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, 1<<20)
+	header, _, err := parseFITSHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bitpix, _ := header.Int("BITPIX")
+	naxis, _ := header.Int("NAXIS")
+	width, _ := header.Int("NAXIS1")
+	height, _ := header.Int("NAXIS2")
+	if naxis < 2 || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("no 2D image in primary HDU (NAXIS=%d)", naxis)
+	}
+	bytesPerPixel := abs(bitpix) / 8
+	if bytesPerPixel == 0 {
+		return nil, fmt.Errorf("unsupported BITPIX %d", bitpix)
+	}
+
+	bzero, _ := header.Float("BZERO")
+	bscale, ok := header.Float("BSCALE")
+	if !ok || bscale == 0 {
+		bscale = 1
+	}
+
+	bin := 1
+	if header.String("BAYERPAT") != "" {
+		bin = 2
+	}
+	for max(width, height)/bin > maxAnalysisSize {
+		bin *= 2
+	}
+
+	img := &fitsImage{Width: width / bin, Height: height / bin, Bin: bin, Header: header}
+	img.Data = make([]float32, img.Width*img.Height)
+	row := make([]byte, width*bytesPerPixel)
+	binArea := float32(bin * bin)
+
+	for y := 0; y < img.Height*bin; y++ {
+		if _, err := io.ReadFull(r, row); err != nil {
+			return nil, fmt.Errorf("truncated image data: %w", err)
+		}
+		out := img.Data[(y/bin)*img.Width : (y/bin+1)*img.Width]
+		for x := 0; x < img.Width*bin; x++ {
+			v := decodeFITSPixel(row[x*bytesPerPixel:], bitpix)*bscale + bzero
+			if math.IsNaN(v) {
+				v = 0
+			}
+			out[x/bin] += float32(v) / binArea
+		}
+	}
+	return img, nil
+}
+
+// decodeFITSPixel decodes one big-endian pixel of the given BITPIX
+func decodeFITSPixel(b []byte, bitpix int) float64 {
+	switch bitpix {
+	case 8:
+		return float64(b[0])
+	case 16:
+		return float64(int16(binary.BigEndian.Uint16(b)))
+	case 32:
+		return float64(int32(binary.BigEndian.Uint32(b)))
+	case 64:
+		return float64(int64(binary.BigEndian.Uint64(b)))
+	case -32:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case -64:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Default tolerances between a light and the flats that can calibrate it
+const (
+	defaultRotatorTolerance = 1.0   // degrees
+	defaultFocuserTolerance = 100.0 // focuser steps
+)
+
+// flatsConfig holds the flat pairing tolerances from astrosession.json
+type flatsConfig struct {
+	RotatorTolerance float64 `json:"rotator_tolerance_deg,omitempty"`
+	FocuserTolerance float64 `json:"focuser_tolerance_steps,omitempty"`
+}
+
+// opticalSetup is what must not change between a light and its flats.
+// Unknown numeric values are NaN and are not compared.
+type opticalSetup struct {
+	Camera  string
+	Filter  string
+	Binning float64
+	Rotator float64
+	Focuser float64
+}
+
+func init() {
+	registerCommand("check-flats", "check-flats <Night_ session folder>",
+		"Warn about light groups without matching flats (filter, rotator angle, focus, binning, camera) and flats without lights.",
+		runCheckFlatsCommand)
+}
+
+// setupFromHeader reads the optical train keywords of a frame
+func setupFromHeader(h fitsHeader) opticalSetup {
+	value := func(keys ...string) float64 {
+		for _, k := range keys {
+			if v, ok := h.Float(k); ok {
+				return v
+			}
+		}
+		return math.NaN()
+	}
+	return opticalSetup{
+		Camera:  strings.TrimSpace(h.String("INSTRUME")),
+		Filter:  strings.TrimSpace(h.String("FILTER")),
+		Binning: value("XBINNING", "BINNING"),
+		Rotator: value("ROTATANG", "ROTANGLE", "ROTATOR"),
+		Focuser: value("FOCPOS", "FOCUSPOS", "FOCUSER"),
+	}
+}
+
+// describe formats a setup for the console
+func (s opticalSetup) describe() string {
+	var parts []string
+	filter := s.Filter
+	if filter == "" {
+		filter = "none"
+	}
+	parts = append(parts, "filter "+filter)
+	if !math.IsNaN(s.Rotator) {
+		parts = append(parts, fmt.Sprintf("rotator %.1f°", s.Rotator))
+	}
+	if !math.IsNaN(s.Focuser) {
+		parts = append(parts, fmt.Sprintf("focus %.0f", s.Focuser))
+	}
+	if !math.IsNaN(s.Binning) {
+		parts = append(parts, fmt.Sprintf("bin %g", s.Binning))
+	}
+	if s.Camera != "" {
+		parts = append(parts, s.Camera)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// pairsWith reports whether a flat with setup s can calibrate a light with setup light
+func (s opticalSetup) pairsWith(light opticalSetup, tol flatsConfig) bool {
+	if s.Camera != "" && light.Camera != "" && !strings.EqualFold(s.Camera, light.Camera) {
+		return false
+	}
+	if !strings.EqualFold(s.Filter, light.Filter) {
+		return false
+	}
+	if !math.IsNaN(s.Binning) && !math.IsNaN(light.Binning) && s.Binning != light.Binning {
+		return false
+	}
+	if !math.IsNaN(s.Rotator) && !math.IsNaN(light.Rotator) {
+		diff := math.Mod(math.Abs(s.Rotator-light.Rotator), 360)
+		if math.Min(diff, 360-diff) > tol.RotatorTolerance {
+			return false
+		}
+	}
+	if !math.IsNaN(s.Focuser) && !math.IsNaN(light.Focuser) && math.Abs(s.Focuser-light.Focuser) > tol.FocuserTolerance {
+		return false
+	}
+	return true
+}
+
+// readSetups reads the optical setup of every frame in the given files
+func readSetups(files []string) []opticalSetup {
+	var setups []opticalSetup
+	for _, path := range files {
+		if header, err := readFrameHeader(path); err == nil {
+			setups = append(setups, setupFromHeader(header))
+		}
+	}
+	return setups
+}
+
+// validateFlatPairing returns a warning for every light group without usable
+// flats and for every flat filter without lights. Lights are grouped by filter,
+// camera, binning and rotator angle (to the tolerance); focus changes with every
+// autofocus run, so each group reports the focus range of its unpaired lights.
+func validateFlatPairing(lights, flats []opticalSetup, tol flatsConfig) []string {
+	var warnings []string
+
+	type unpairedGroup struct {
+		count              int
+		focusMin, focusMax float64
+	}
+	unpaired := map[string]*unpairedGroup{}
+	lightFilters := map[string]bool{}
+	for _, l := range lights {
+		lightFilters[strings.ToLower(l.Filter)] = true
+		paired := false
+		for _, f := range flats {
+			if f.pairsWith(l, tol) {
+				paired = true
+				break
+			}
+		}
+		if paired {
+			continue
+		}
+
+		group := l
+		group.Focuser = math.NaN()
+		if !math.IsNaN(group.Rotator) && tol.RotatorTolerance > 0 {
+			group.Rotator = math.Mod(math.Round(group.Rotator/tol.RotatorTolerance)*tol.RotatorTolerance, 360)
+		}
+		key := group.describe()
+		g := unpaired[key]
+		if g == nil {
+			g = &unpairedGroup{focusMin: math.NaN(), focusMax: math.NaN()}
+			unpaired[key] = g
+		}
+		g.count++
+		if !math.IsNaN(l.Focuser) {
+			if math.IsNaN(g.focusMin) || l.Focuser < g.focusMin {
+				g.focusMin = l.Focuser
+			}
+			if math.IsNaN(g.focusMax) || l.Focuser > g.focusMax {
+				g.focusMax = l.Focuser
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(unpaired))
+	for k := range unpaired {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g := unpaired[k]
+		desc := k
+		switch {
+		case math.IsNaN(g.focusMin):
+		case g.focusMin == g.focusMax:
+			desc += fmt.Sprintf(", focus %.0f", g.focusMin)
+		default:
+			desc += fmt.Sprintf(", focus %.0f-%.0f", g.focusMin, g.focusMax)
+		}
+		warnings = append(warnings, tr("%d lights (%s) have no matching flats", g.count, desc))
+	}
+
+	orphanFlats := map[string]int{}
+	for _, f := range flats {
+		if !lightFilters[strings.ToLower(f.Filter)] {
+			orphanFlats[f.Filter]++
+		}
+	}
+	filters := make([]string, 0, len(orphanFlats))
+	for f := range orphanFlats {
+		filters = append(filters, f)
+	}
+	sort.Strings(filters)
+	for _, f := range filters {
+		name := f
+		if name == "" {
+			name = "none"
+		}
+		warnings = append(warnings, tr("%d flats with filter %s have no lights", orphanFlats[f], name))
+	}
+	return warnings
+}
+
+// printFlatValidation prints the result of validateFlatPairing and returns the number of warnings
+func printFlatValidation(lights, flats []opticalSetup, tol flatsConfig) int {
+	if len(lights) == 0 && len(flats) == 0 {
+		return 0
+	}
+	warnings := validateFlatPairing(lights, flats, tol)
+	if len(warnings) == 0 {
+		trPrintf("✅ Flats check: %d lights and %d flats pair correctly.\n", len(lights), len(flats))
+		return 0
+	}
+	fmt.Println(tr("⚠️  Flats check:"))
+	for _, w := range warnings {
+		fmt.Printf("   - %s\n", w)
+	}
+	return len(warnings)
+}
+
+func runCheckFlatsCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["check-flats"].usage)
+	}
+	_, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	sessionPath := cleanPath(args[0])
+	lights := readSetups(listFrameFiles(filepath.Join(sessionPath, "Lights")))
+	flats := readSetups(listFrameFiles(filepath.Join(sessionPath, "Flats")))
+	if len(lights) == 0 && len(flats) == 0 {
+		return fmt.Errorf("no lights or flats found in '%s'", sessionPath)
+	}
+	printFlatValidation(lights, flats, cfg.Flats)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestValidateFlatPairing(t *testing.T) {
+	nan := math.NaN()
+	setup := func(filter string, rotator, focuser float64) opticalSetup {
+		return opticalSetup{Camera: "ASI2600MM", Filter: filter, Binning: 1, Rotator: rotator, Focuser: focuser}
+	}
+	tol := flatsConfig{RotatorTolerance: 1, FocuserTolerance: 100}
+	tests := []struct {
+		name   string
+		lights []opticalSetup
+		flats  []opticalSetup
+		want   []string
+	}{
+		{
+			name:   "autofocus runs within tolerance",
+			lights: []opticalSetup{setup("Ha", 90, 10010), setup("Ha", 90.2, 10040), setup("Ha", 89.9, 10075)},
+			flats:  []opticalSetup{setup("Ha", 90, 10050)},
+		},
+		{
+			name:   "focus drift beyond tolerance is one group",
+			lights: []opticalSetup{setup("Ha", 90, 10010), setup("Ha", 90.2, 10300), setup("Ha", 89.9, 10420)},
+			flats:  []opticalSetup{setup("Ha", 90, 10050)},
+			want:   []string{"2 lights (filter Ha, rotator 90.0°, bin 1, ASI2600MM, focus 10300-10420) have no matching flats"},
+		},
+		{
+			name:   "rotated lights and orphan flats",
+			lights: []opticalSetup{setup("OIII", 180, nan)},
+			flats:  []opticalSetup{setup("OIII", 90, nan), setup("SII", 180, nan)},
+			want: []string{
+				"1 lights (filter OIII, rotator 180.0°, bin 1, ASI2600MM) have no matching flats",
+				"1 flats with filter SII have no lights",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validateFlatPairing(tt.lights, tt.flats, tol)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
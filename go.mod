@@ -1,3 +1,7 @@
 module astrosession
 
 go 1.22.5
+
+require github.com/fsnotify/fsnotify v1.9.0
+
+require golang.org/x/sys v0.13.0 // indirect
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message catalogs: English source text -> translation. English needs no catalog.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Languages with a catalog (UI) and month folder names
+var supportedLanguages = []string{"en", "es", "fr"}
+
+// Month folder abbreviations per language (capture_layout {month} token)
+var folderMonthNames = map[string][12]string{
+	"en": {"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	"es": {"Ene", "Feb", "Mar", "Abr", "May", "Jun", "Jul", "Ago", "Sep", "Oct", "Nov", "Dic"},
+	"fr": {"Janv", "Févr", "Mars", "Avr", "Mai", "Juin", "Juil", "Août", "Sept", "Oct", "Nov", "Déc"},
+}
+
+var (
+	uiLanguage     = "en"
+	folderLanguage = "en"
+	messages       map[string]string
+)
+
+// LANG applies until the configuration is loaded (e.g. to the usage of a subcommand)
+func init() {
+	applyLanguage(userConfig{})
+}
+
+// languageCode reduces a language setting ("es_ES.UTF-8", "fr-CA", "ES") to a
+// supported code, or "" when there is no catalog for it
+func languageCode(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) > 2 {
+		value = value[:2]
+	}
+	for _, l := range supportedLanguages {
+		if l == value {
+			return l
+		}
+	}
+	return ""
+}
+
+// detectLanguage returns the UI language: the configured one, else the first
+// of LC_ALL, LC_MESSAGES and LANG the tool has a catalog for, else English
+func detectLanguage(configured string) string {
+	if l := languageCode(configured); l != "" {
+		return l
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if l := languageCode(os.Getenv(env)); l != "" {
+			return l
+		}
+	}
+	return "en"
+}
+
+// applyLanguage selects the UI and folder name languages from the configuration.
+// Folder names stay in English unless folder_language says otherwise, so an
+// archive keeps the same layout whoever runs the tool.
+func applyLanguage(cfg userConfig) {
+	uiLanguage = detectLanguage(cfg.Language)
+	folderLanguage = "en"
+	if l := languageCode(cfg.FolderLanguage); l != "" {
+		folderLanguage = l
+	}
+
+	messages = nil
+	if uiLanguage == "en" {
+		return
+	}
+	data, err := localeFiles.ReadFile("locales/" + uiLanguage + ".json")
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &messages); err != nil {
+		fmt.Printf("⚠️  Broken %s message catalog: %v\n", uiLanguage, err)
+	}
+}
+
+// tr translates a message of the UI and formats it like fmt.Sprintf. Leading
+// and trailing line breaks are kept out of the catalog keys.
+func tr(format string, args ...any) string {
+	body := strings.TrimLeft(format, "\n")
+	prefix := format[:len(format)-len(body)]
+	core := strings.TrimRight(body, "\n")
+	suffix := body[len(core):]
+	if t, ok := messages[core]; ok && t != "" {
+		format = prefix + t + suffix
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// trPrintf prints a translated message
+func trPrintf(format string, args ...any) {
+	fmt.Print(tr(format, args...))
+}
+
+// answerYes reports whether a y/n prompt was answered yes in any UI language
+func answerYes(resp string) bool {
+	switch strings.ToLower(strings.TrimSpace(resp)) {
+	case "y", "yes", "s", "si", "sí", "o", "oui":
+		return true
+	}
+	return false
+}
+
+// answerNo reports whether a prompt that defaults to yes was answered no in any UI language
+func answerNo(resp string) bool {
+	switch strings.ToLower(strings.TrimSpace(resp)) {
+	case "n", "no", "non":
+		return true
+	}
+	return false
+}
+
+// monthFolderName returns the {month} folder of a month in the folder language
+func monthFolderName(m time.Month) string {
+	return folderMonthNames[folderLanguage][m-1]
+}
+
+// formatNightDate prints a date for the UI ("Monday 2 January 2006")
+func formatNightDate(t time.Time) string {
+	return tr("%s %d %s %d", tr(t.Weekday().String()), t.Day(), tr(t.Month().String()), t.Year())
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand("import", "import [-dry-run] [-coord-tol 0.5] [-equipment name] [-site name] <legacy folder>",
+		"Reorganize an old archive: every frame below the folder is grouped by OBJECT, night, frame type and filter (folder names stand in for a missing OBJECT or DATE-OBS), targets are resolved like the interactive creator and, after a plan preview, lights/flats move into Target/Year/Month/Night_DD sessions and darks/bias into the calibration library.",
+		runImportCommand)
+}
+
+// legacyHints is what the folder names of a legacy archive tell about a frame
+type legacyHints struct {
+	Object   string // catalog designation
+	Night    time.Time
+	HasNight bool
+}
+
+var (
+	reFolderDate = regexp.MustCompile(`(?:^|\D)((?:19|20)\d{2})[-_.]?(\d{2})[-_.]?(\d{2})(?:\D|$)`)
+	reFolderYear = regexp.MustCompile(`^(?:19|20)\d{2}$`)
+	reFolderDay  = regexp.MustCompile(`(?i)^(?:night[-_ ]?)?(\d{1,2})(?:\D|$)`)
+	// Barnard (B) and Caldwell (C) are left out: "C8", "C11" or "B2" in a legacy
+	// archive are far more often Celestron tubes or other gear than objects
+	reFolderDesignation = regexp.MustCompile(`(?i)^(?:M|NGC|IC|Sh2|LDN|LBN|vdB|Abell|Arp|Mel|Cr)[-_ ]*\d+`)
+)
+
+func runImportCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only show the plan (targets resolved without prompting)")
+	coordTol := fs.Float64("coord-tol", defaultCoordTolerance, "degrees under which lights without OBJECT are grouped together")
+	equipmentName := fs.String("equipment", "", "equipment profile name from "+userConfigFile+" (default: detected from the headers)")
+	siteName := fs.String("site", cfg.DefaultSite, "observing site name from "+userConfigFile)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: " + commands["import"].usage)
+	}
+
+	opts, err := newIngestOptions(baseDir, cfg, *equipmentName, *siteName)
+	if err != nil {
+		return err
+	}
+	opts.CoordTol, opts.DryRun = *coordTol, *dryRun
+	src, err := ingestSource(baseDir, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	opts.LegacyRoot = src
+
+	trPrintf("🗂️  Importing %s into %s\n", src, baseDir)
+	paths := listFrameFiles(src)
+	if len(paths) == 0 {
+		return fmt.Errorf("no frames found in '%s'", src)
+	}
+	scan := scanIngestFrames(paths, opts)
+	if len(scan.Groups) == 0 && len(scan.Calibration) == 0 {
+		return fmt.Errorf("no lights, darks or bias found in '%s'", src)
+	}
+	return fileIngestScan(scan, opts)
+}
+
+// legacyPathHints reads the target and night of a frame from the folders
+// between the legacy root and the file, the nearest folder first: dates like
+// "2019-10-05", "20191005" or "M31_2019-10-05", this tool's own
+// "2019/Oct/Night_05", and folders named after a catalogued object
+func legacyPathHints(root, path string) legacyHints {
+	var hints legacyHints
+	rel, err := filepath.Rel(root, filepath.Dir(path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return hints
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if m := reFolderDate.FindStringSubmatch(seg); m != nil {
+			if night, ok := validDate(atoi(m[1]), atoi(m[2]), atoi(m[3])); ok && !hints.HasNight {
+				hints.Night, hints.HasNight = night, true
+			}
+			seg = strings.Trim(strings.Replace(seg, strings.Trim(m[0], "-_. "), "", 1), "-_. ")
+		}
+		if !hints.HasNight && reFolderYear.MatchString(seg) && i+2 < len(segments) {
+			month, isMonth := parseMonthFolder(segments[i+1])
+			day := reFolderDay.FindStringSubmatch(segments[i+2])
+			if isMonth && day != nil {
+				if night, ok := validDate(atoi(seg), int(month), atoi(day[1])); ok {
+					hints.Night, hints.HasNight = night, true
+				}
+			}
+		}
+		if hints.Object == "" && seg != "" {
+			hints.Object = folderObject(seg)
+		}
+	}
+	return hints
+}
+
+// folderObject returns the object a folder is named after: a designation at the
+// start of the name ("M31", "NGC 7000 wide") or a common name of the embedded
+// catalog ("Andromeda Galaxy"), "" for anything else
+func folderObject(name string) string {
+	if d := reFolderDesignation.FindString(name); d != "" {
+		return strings.TrimSpace(d)
+	}
+	key := normalizeName(name)
+	for _, e := range loadDSOCatalog() {
+		if e.CommonName != "" && normalizeName(e.CommonName) == key {
+			return e.Designation
+		}
+	}
+	return ""
+}
+
+// validDate returns the date of a year, month and day when it exists
+func validDate(year, month, day int) (time.Time, bool) {
+	if month < 1 || month > 12 || day < 1 {
+		return time.Time{}, false
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	return date, date.Day() == day
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default distance (degrees) under which lights without OBJECT belong to the same target
+const defaultCoordTolerance = 0.5
+
+func init() {
+	registerCommand("ingest", "ingest [-dry-run] [-coord-tol 0.5] [-equipment name] [-site name] <folder>",
+		"Group the lights of a folder by their OBJECT keyword (or by pointing when it is blank), resolve each group like the interactive creator and move every frame into its Target/Night_ session.",
+		runIngestCommand)
+}
+
+// ingestFrame is a light or flat waiting to be filed
+type ingestFrame struct {
+	Path      string
+	Folder    string // "Lights" or "Flats"
+	Filter    string
+	DateObs   string
+	Night     time.Time
+	Equipment *equipmentProfile
+}
+
+// ingestGroup is a set of lights of the same target and the flats assigned to it
+type ingestGroup struct {
+	Object string       // OBJECT value without panel suffix, empty for groups made by coordinates
+	Panel  string       // mosaic panel id ("02"), empty for regular targets
+	Pos    *skyPosition // pointing of the first light, nil if unknown
+	Frames []ingestFrame
+	Name   string // name resolved for the folder (OBJECT, chosen or typed by the user)
+	Target resolvedTarget
+}
+
+// label describes the group in the plan
+func (g *ingestGroup) label() string {
+	if g.Object != "" && g.Panel != "" {
+		return fmt.Sprintf("OBJECT '%s', %s", g.Object, panelFolder(g.Panel))
+	}
+	if g.Object != "" {
+		return fmt.Sprintf("OBJECT '%s'", g.Object)
+	}
+	if g.Pos != nil {
+		return tr("no OBJECT, pointing %s", formatCoordinates(g.Pos.RA, g.Pos.Dec))
+	}
+	return tr("no OBJECT and no coordinates")
+}
+
+// counts returns the number of lights and flats of the group
+func (g *ingestGroup) counts() (lights, flats int) {
+	for _, f := range g.Frames {
+		if f.Folder == "Flats" {
+			flats++
+		} else {
+			lights++
+		}
+	}
+	return lights, flats
+}
+
+// nights lists the nights of the group's frames, sorted
+func (g *ingestGroup) nights() []string {
+	var nights []string
+	for _, f := range g.Frames {
+		nights = appendUnique(nights, f.Night.Format("2006-01-02"))
+	}
+	sort.Strings(nights)
+	return nights
+}
+
+// ingestOptions are the settings shared by ingest and import
+type ingestOptions struct {
+	BaseDir   string
+	Cfg       userConfig
+	Site      *siteProfile
+	Equipment *equipmentProfile // forced with -equipment, nil detects it per frame
+	CoordTol  float64
+	DryRun    bool
+
+	// import only: the legacy folder, whose folder names stand in for a missing
+	// OBJECT or DATE-OBS, and whose darks/bias go to the calibration library
+	LegacyRoot string
+}
+
+// ingestScan is what the frames of a folder turned into before resolving the targets
+type ingestScan struct {
+	Groups      []*ingestGroup
+	Flats       []ingestFrame
+	FlatObjects map[string]string // flat path -> OBJECT, when it names a target
+	Calibration []moveJob         // darks/bias bound for the library (import)
+	Skipped     int
+	Total       int
+}
+
+func runIngestCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only show the plan (targets resolved without prompting)")
+	coordTol := fs.Float64("coord-tol", defaultCoordTolerance, "degrees under which lights without OBJECT are grouped together")
+	equipmentName := fs.String("equipment", "", "equipment profile name from "+userConfigFile+" (default: detected from the headers)")
+	siteName := fs.String("site", cfg.DefaultSite, "observing site name from "+userConfigFile)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: " + commands["ingest"].usage)
+	}
+
+	opts, err := newIngestOptions(baseDir, cfg, *equipmentName, *siteName)
+	if err != nil {
+		return err
+	}
+	opts.CoordTol, opts.DryRun = *coordTol, *dryRun
+	src, err := ingestSource(baseDir, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	paths := listFrameFiles(src)
+	if len(paths) == 0 {
+		return fmt.Errorf("no frames found in '%s'", src)
+	}
+	scan := scanIngestFrames(paths, opts)
+	if len(scan.Groups) == 0 {
+		return fmt.Errorf("no lights found in '%s'", src)
+	}
+	return fileIngestScan(scan, opts)
+}
+
+// newIngestOptions looks up the -equipment and -site profiles
+func newIngestOptions(baseDir string, cfg userConfig, equipmentName, siteName string) (ingestOptions, error) {
+	opts := ingestOptions{BaseDir: baseDir, Cfg: cfg, Site: cfg.findSite(siteName), CoordTol: defaultCoordTolerance}
+	if equipmentName != "" {
+		if opts.Equipment = cfg.findEquipment(equipmentName); opts.Equipment == nil {
+			return opts, fmt.Errorf("equipment profile '%s' not found in %s", equipmentName, userConfigFile)
+		}
+	}
+	return opts, nil
+}
+
+// ingestSource returns the absolute folder to take frames from, refusing one that contains the archive
+func ingestSource(baseDir, arg string) (string, error) {
+	src, err := filepath.Abs(cleanPath(arg))
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("'%s' is not a folder", src)
+	}
+	if rel, err := filepath.Rel(src, baseDir); err == nil && !strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("the archive %s can't be inside the folder to ingest", baseDir)
+	}
+	return src, nil
+}
+
+// scanIngestFrames reads the headers of the frames and groups the lights by
+// target, keeping the flats (and, on import, the darks/bias) aside
+func scanIngestFrames(paths []string, opts ingestOptions) *ingestScan {
+	cfg := opts.Cfg
+	scan := &ingestScan{FlatObjects: map[string]string{}, Total: len(paths)}
+	for _, path := range paths {
+		header, err := readFrameHeader(path)
+		if err != nil {
+			trPrintf("⚠️  %s: %v\n", filepath.Base(path), err)
+			scan.Skipped++
+			continue
+		}
+		var hints legacyHints
+		if opts.LegacyRoot != "" {
+			hints = legacyPathHints(opts.LegacyRoot, path)
+		}
+
+		folder := "Lights"
+		switch frameType := imageType(header); frameType {
+		case frameLight, "":
+		case frameFlat:
+			folder = "Flats"
+		case frameDark, frameBias:
+			if opts.LegacyRoot != "" {
+				rel := signatureFromHeader(header).libraryDir(frameType)
+				scan.Calibration = append(scan.Calibration, moveJob{Src: path, DestDir: filepath.Join(opts.BaseDir, calibrationFolder, rel)})
+				continue
+			}
+			scan.Skipped++ // darks/bias belong to the calibration library
+			continue
+		default:
+			scan.Skipped++
+			continue
+		}
+
+		night, err := frameNight(header, path, opts.Site)
+		if _, hasDate := dateObs(header); !hasDate && header.String("DATE-LOC") == "" && hints.HasNight {
+			night, err = hints.Night, nil
+		}
+		if err != nil {
+			scan.Skipped++
+			continue
+		}
+		equipment := opts.Equipment
+		if equipment == nil {
+			equipment = cfg.detectEquipment(header)
+		}
+		if equipment == nil {
+			equipment = cfg.findEquipment(cfg.DefaultEquipment)
+		}
+		frame := ingestFrame{Path: path, Folder: folder, Filter: header.String("FILTER"), DateObs: header.String("DATE-OBS"), Night: night, Equipment: equipment}
+
+		object := strings.TrimSpace(header.String("OBJECT"))
+		if isPlaceholderObject(object) && hints.Object != "" {
+			object = hints.Object
+		}
+		if folder == "Flats" {
+			if !isPlaceholderObject(object) {
+				scan.FlatObjects[path] = object
+			}
+			scan.Flats = append(scan.Flats, frame)
+			continue
+		}
+		pos := framePosition(header, object)
+		if _, _, isCoords := parseCoordinateInput(object); isCoords || isPlaceholderObject(object) {
+			object = ""
+		}
+		object, panel, _ := parsePanelObject(object)
+		g := findIngestGroup(scan.Groups, object, panel, pos, opts.CoordTol)
+		if g == nil {
+			g = &ingestGroup{Object: object, Panel: panel, Pos: pos}
+			scan.Groups = append(scan.Groups, g)
+		}
+		g.Frames = append(g.Frames, frame)
+	}
+	return scan
+}
+
+// fileIngestScan resolves the targets of the scanned groups, shows the plan and,
+// once confirmed, moves every frame into its session
+func fileIngestScan(scan *ingestScan, opts ingestOptions) error {
+	baseDir, cfg, site := opts.BaseDir, opts.Cfg, opts.Site
+	groups := scan.Groups
+
+	reader := bufio.NewReader(os.Stdin)
+	assignFlats(reader, groups, scan.Flats, scan.FlatObjects, opts.DryRun)
+
+	skippedKinds := tr("darks, bias or unreadable")
+	if opts.LegacyRoot != "" {
+		skippedKinds = tr("unreadable or of an unknown type")
+	}
+	trPrintf("\n%d frames found, %d target groups (%d frames skipped: %s):\n", scan.Total, len(groups), scan.Skipped, skippedKinds)
+	for i, g := range groups {
+		nLights, nFlats := g.counts()
+		trPrintf("  %d) %s: %d lights, %d flats, nights %s\n", i+1, g.label(), nLights, nFlats, strings.Join(g.nights(), ", "))
+	}
+
+	// Resolve every group through Sesame and the similar-folder check. Groups
+	// without a usable OBJECT get the catalogued objects near their pointing.
+	// Panels of a mosaic share the target resolved for the first one.
+	var resolved []*ingestGroup
+	byObject := map[string]*ingestGroup{}
+	for i, g := range groups {
+		if first, ok := byObject[normalizeName(g.Object)]; ok && g.Object != "" {
+			g.Name, g.Target = first.Name, first.Target
+			resolved = append(resolved, g)
+			continue
+		}
+		if opts.DryRun {
+			name := g.Object
+			if name == "" && g.Pos != nil {
+				if matches := lookupNearbyObjects(g.Pos.RA, g.Pos.Dec); len(matches) > 0 {
+					name = matches[0].Designation
+				}
+			}
+			if name == "" {
+				trPrintf("\nGroup %d (%s) needs a name; skipped in the dry run.\n", i+1, g.label())
+				continue
+			}
+			g.Name = name
+			g.Target = resolveTargetAuto(baseDir, name, g.Pos, cfg.FieldNaming)
+			resolved = append(resolved, g)
+			byObject[normalizeName(g.Object)] = g
+			continue
+		}
+
+		trPrintf("\n--- Group %d: %s ---\n", i+1, g.label())
+		names := []string{g.Object}
+		if g.Object == "" || (g.Pos != nil && !resolveObject(g.Object).Found) {
+			if g.Object != "" {
+				trPrintf("-> '%s' is not a known object.\n", g.Object)
+			}
+			if g.Pos != nil {
+				names = chooseNearbyObject(reader, g.Pos.RA, g.Pos.Dec)
+			} else {
+				fmt.Print(tr("Target name (empty skips it): "))
+				names = strings.Fields(readInput(reader))
+			}
+			if len(names) == 0 {
+				continue
+			}
+		}
+		g.Name = strings.Join(names, " ")
+		g.Target = resolveTargetInteractive(reader, baseDir, g.Name, names, cfg.FieldNaming)
+		resolved = append(resolved, g)
+		byObject[normalizeName(g.Object)] = g
+	}
+
+	// Plan: one session per target, night and equipment
+	type plannedSession struct {
+		capturePath, rejectedBase string
+		target                    resolvedTarget
+		panel                     string
+		equipment                 *equipmentProfile
+		tokens                    layoutTokens
+		input, dateInput          string
+		contents                  map[string]int // frames per folder ("Lights/Ha")
+	}
+	sessions := map[string]*plannedSession{}
+	var order []string
+	var jobs []moveJob
+	for _, g := range resolved {
+		for _, f := range g.Frames {
+			tokens := cfg.nightTokens(f.Night, f.Equipment, site)
+			rel := expandLayout(cfg.CaptureLayout, tokens)
+			capturePath, rejectedBase := sessionPaths(baseDir, g.Target.Folder, g.Panel, rel)
+			if _, ok := sessions[capturePath]; !ok {
+				sessions[capturePath] = &plannedSession{
+					capturePath:  capturePath,
+					rejectedBase: rejectedBase,
+					target:       g.Target,
+					panel:        g.Panel,
+					equipment:    f.Equipment,
+					tokens:       tokens,
+					input:        g.Name,
+					dateInput:    f.DateObs,
+					contents:     map[string]int{},
+				}
+				order = append(order, capturePath)
+			}
+			destDir := filepath.Join(capturePath, f.Folder)
+			if cfg.PerFilterFolders && f.Filter != "" {
+				destDir = filepath.Join(destDir, safeFolderPart(f.Filter))
+			}
+			jobs = append(jobs, moveJob{Src: f.Path, DestDir: destDir})
+			content := f.Folder
+			if f.Filter != "" {
+				content += "/" + f.Filter
+			}
+			sessions[capturePath].contents[content]++
+		}
+	}
+	if len(jobs) == 0 && len(scan.Calibration) == 0 {
+		fmt.Println(tr("\nNothing to move."))
+		return nil
+	}
+
+	trPrintf("\nPlan: %d frames into %d sessions:\n", len(jobs), len(order))
+	for _, path := range order {
+		rel, _ := filepath.Rel(baseDir, path)
+		fmt.Printf("  📁 %s (%s)\n", filepath.ToSlash(rel), describeContents(sessions[path].contents))
+	}
+	libraryDirs := map[string]int{}
+	for _, j := range scan.Calibration {
+		libraryDirs[j.DestDir]++
+	}
+	if len(libraryDirs) > 0 {
+		trPrintf("\n%d darks/bias into the calibration library:\n", len(scan.Calibration))
+		for _, dir := range sortedKeys(libraryDirs) {
+			rel, _ := filepath.Rel(baseDir, dir)
+			trPrintf("  📚 %s (%d frames)\n", filepath.ToSlash(rel), libraryDirs[dir])
+		}
+		jobs = append(jobs, scan.Calibration...)
+	}
+	if opts.DryRun {
+		return nil
+	}
+	fmt.Print(tr("\nMove the frames? (y/n) [y]: "))
+	if answerNo(readInput(reader)) {
+		fmt.Println(tr("Operation canceled."))
+		return nil
+	}
+
+	for _, path := range order {
+		s := sessions[path]
+		if err := prepareTargetRoot(filepath.Join(baseDir, s.target.Folder), s.target.Objects); err != nil {
+			return err
+		}
+		if err := cfg.createSessionTree(s.capturePath, s.rejectedBase, s.equipment); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(tr("\nStarting transfer..."))
+	moved := moveWithProgress(jobs)
+
+	for _, path := range order {
+		s := sessions[path]
+		var files []movedFile
+		for _, m := range moved {
+			if strings.HasPrefix(m.Path, s.capturePath+string(filepath.Separator)) {
+				files = append(files, m)
+			}
+		}
+
+		meta := openSessionMetadata(s.capturePath)
+		meta.Target = sessionTarget{Input: s.input, Folder: s.target.Folder, Objects: s.target.Objects}
+		meta.Date = sessionDate{Input: s.dateInput, Year: s.tokens.Year, Month: s.tokens.Month, Day: s.tokens.Day}
+		if s.equipment != nil {
+			meta.Equipment = s.equipment
+		}
+		if site != nil {
+			meta.Site = site
+		}
+		meta.Panel = s.panel
+		meta.addFiles(s.capturePath, files)
+		if err := meta.save(s.capturePath); err != nil {
+			trPrintf("⚠️  Could not write %s: %v\n", sessionMetadataFile, err)
+		}
+		syncMirrorFolders(s.capturePath, s.rejectedBase)
+
+		rel, _ := filepath.Rel(baseDir, s.capturePath)
+		trPrintf("\n✅ %s: %d frames\n", filepath.ToSlash(rel), len(files))
+		printFlatValidation(
+			readSetups(listFrameFiles(filepath.Join(s.capturePath, "Lights"))),
+			readSetups(listFrameFiles(filepath.Join(s.capturePath, "Flats"))),
+			cfg.Flats)
+	}
+	if len(scan.Calibration) > 0 {
+		library := filepath.Join(baseDir, calibrationFolder) + string(filepath.Separator)
+		count := 0
+		for _, m := range moved {
+			if strings.HasPrefix(m.Path, library) {
+				count++
+			}
+		}
+		trPrintf("\n✅ %s: %d darks/bias\n", calibrationFolder, count)
+	}
+	return nil
+}
+
+// describeContents lists the frames of a planned session per folder and filter
+func describeContents(contents map[string]int) string {
+	total := 0
+	var parts []string
+	for _, folder := range sortedKeys(contents) {
+		total += contents[folder]
+		parts = append(parts, fmt.Sprintf("%s %d", folder, contents[folder]))
+	}
+	return tr("%d frames: %s", total, strings.Join(parts, ", "))
+}
+
+// findIngestGroup returns the group of a light: same OBJECT (ignoring case and
+// spacing) and mosaic panel or, without OBJECT, a pointing within tol degrees
+func findIngestGroup(groups []*ingestGroup, object, panel string, pos *skyPosition, tol float64) *ingestGroup {
+	for _, g := range groups {
+		if object != "" {
+			if normalizeName(g.Object) == normalizeName(object) && g.Panel == panel {
+				return g
+			}
+			continue
+		}
+		if g.Object != "" {
+			continue
+		}
+		if pos != nil && g.Pos != nil && angularSeparation(pos.RA, pos.Dec, g.Pos.RA, g.Pos.Dec) <= tol {
+			return g
+		}
+		if pos == nil && g.Pos == nil {
+			return g
+		}
+	}
+	return nil
+}
+
+// assignFlats gives each flat to the light group it belongs to: the one named by
+// its OBJECT, the only group imaged that night, or the one the user picks
+func assignFlats(reader *bufio.Reader, groups []*ingestGroup, flats []ingestFrame, flatObjects map[string]string, dryRun bool) {
+	byNight := map[string][]ingestFrame{}
+	var nights []string
+	for _, f := range flats {
+		if object, ok := flatObjects[f.Path]; ok {
+			object, panel, _ := parsePanelObject(object)
+			if g := findIngestGroup(groups, object, panel, nil, 0); g != nil {
+				g.Frames = append(g.Frames, f)
+				continue
+			}
+		}
+		night := f.Night.Format("2006-01-02")
+		if _, ok := byNight[night]; !ok {
+			nights = append(nights, night)
+		}
+		byNight[night] = append(byNight[night], f)
+	}
+	sort.Strings(nights)
+
+	for _, night := range nights {
+		var candidates []*ingestGroup
+		for _, g := range groups {
+			for _, n := range g.nights() {
+				if n == night {
+					candidates = append(candidates, g)
+					break
+				}
+			}
+		}
+		switch {
+		case len(candidates) == 0:
+			trPrintf("⚠️  %d flats of %s have no lights that night; they stay in place.\n", len(byNight[night]), night)
+			continue
+		case len(candidates) == 1:
+			candidates[0].Frames = append(candidates[0].Frames, byNight[night]...)
+			continue
+		case dryRun:
+			trPrintf("⚠️  %d flats of %s: %d targets that night, the target would be asked.\n", len(byNight[night]), night, len(candidates))
+			continue
+		}
+
+		trPrintf("\n%d flats of %s could belong to several targets:\n", len(byNight[night]), night)
+		for i, g := range candidates {
+			fmt.Printf("  %d) %s\n", i+1, g.label())
+		}
+		for {
+			trPrintf("Which target do they belong to? (1-%d, empty leaves them in place): ", len(candidates))
+			resp := readInput(reader)
+			if resp == "" {
+				break
+			}
+			if idx, err := strconv.Atoi(resp); err == nil && idx >= 1 && idx <= len(candidates) {
+				candidates[idx-1].Frames = append(candidates[idx-1].Frames, byNight[night]...)
+				break
+			}
+			fmt.Println(tr("Invalid option."))
+		}
+	}
+}
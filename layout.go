@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Default session path under the target root. Tokens are replaced by
+// expandLayout; segments that end up empty are dropped.
+const defaultCaptureLayout = "{year}/{month}/Night_{day}"
+
+// layoutTokens are the values available to the capture layout
+type layoutTokens struct {
+	Year      string
+	Month     string
+	Day       string
+	Equipment string
+	Site      string
+}
+
+// expandLayout builds the relative session path from a layout template
+func expandLayout(layout string, t layoutTokens) string {
+	replacer := strings.NewReplacer(
+		"{year}", t.Year,
+		"{month}", t.Month,
+		"{day}", t.Day,
+		"{equipment}", t.Equipment,
+		"{site}", t.Site,
+	)
+
+	var segments []string
+	for _, seg := range strings.Split(filepath.ToSlash(layout), "/") {
+		hadToken := strings.Contains(seg, "{")
+		seg = replacer.Replace(seg)
+		if hadToken {
+			// "Night_{day}_{equipment}" without equipment must not leave a dangling "_"
+			seg = strings.Trim(seg, "_- ")
+			if seg != "" {
+				// Equipment and site names are typed by the user
+				seg = safeFolderPart(seg)
+			}
+		}
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return filepath.Join(segments...)
+}
+
+// Styles of the {month} folders (month_folders)
+const (
+	monthFoldersName       = "name"        // "Feb"
+	monthFoldersNumberName = "number-name" // "02-Feb", sorts chronologically
+	monthFoldersNumber     = "number"      // "02"
+)
+
+// validMonthFolders reports whether a month_folders value is a known style ("" is the default)
+func validMonthFolders(style string) bool {
+	switch style {
+	case "", monthFoldersName, monthFoldersNumberName, monthFoldersNumber:
+		return true
+	}
+	return false
+}
+
+var reMonthFolder = regexp.MustCompile(`^(\d{1,2})(?:[-_ ]+(.+))?$`)
+
+// monthFolder returns the {month} folder of a month in the configured style
+func (c userConfig) monthFolder(m time.Month) string {
+	switch c.MonthFolders {
+	case monthFoldersNumberName:
+		return fmt.Sprintf("%02d-%s", int(m), monthFolderName(m))
+	case monthFoldersNumber:
+		return fmt.Sprintf("%02d", int(m))
+	}
+	return monthFolderName(m)
+}
+
+// parseMonthFolder reads a {month} folder written in any style and folder
+// language ("Feb", "Févr", "02-Feb", "02")
+func parseMonthFolder(name string) (time.Month, bool) {
+	if m := reMonthFolder.FindStringSubmatch(name); m != nil {
+		n := atoi(m[1])
+		if n < 1 || n > 12 {
+			return 0, false
+		}
+		if m[2] != "" {
+			if named, ok := parseMonthName(m[2]); !ok || int(named) != n {
+				return 0, false
+			}
+		}
+		return time.Month(n), true
+	}
+	return parseMonthName(name)
+}
+
+// nightTokens returns the layout tokens of a session for a night, equipment and site
+func (c userConfig) nightTokens(night time.Time, equipment *equipmentProfile, site *siteProfile) layoutTokens {
+	tokens := layoutTokens{
+		Year:  fmt.Sprintf("%d", night.Year()),
+		Month: c.monthFolder(night.Month()),
+		Day:   fmt.Sprintf("%02d", night.Day()),
+	}
+	if equipment != nil {
+		tokens.Equipment = equipment.Name
+	}
+	if site != nil {
+		tokens.Site = site.Name
+	}
+	return tokens
+}
+
+// frameNight returns the night a frame was taken in the site's time zone, from
+// DATE-OBS, DATE-LOC (local time, e.g. read from the file name) or, when both
+// are missing, the file's modification time
+func frameNight(header fitsHeader, path string, site *siteProfile) (time.Time, error) {
+	taken, ok := dateObs(header)
+	if !ok {
+		local, dateOnly, found := dateLoc(header, site.location())
+		if found && dateOnly {
+			return local, nil
+		}
+		taken, ok = local, found
+	}
+	if !ok {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		taken = info.ModTime()
+	}
+	return nightDate(taken, site.location()), nil
+}
+
+// Capture folders that don't hold frames and therefore have no Rejected mirror
+var nonFrameFolders = []string{"Logs"}
+
+// Frame folders split into one subfolder per filter when per_filter_folders is enabled
+var filterSplitFolders = []string{"Lights", "Flats"}
+
+// sessionFolders returns every folder created inside a capture path
+// (e.g. "Lights/Ha", "Flats/Ha", "Darks", "Logs") from the configured layout
+func (c userConfig) sessionFolders(equipment *equipmentProfile) []string {
+	var folders []string
+	for _, folder := range c.CaptureFolders {
+		if c.PerFilterFolders && isFilterSplitFolder(folder) && equipment != nil && len(equipment.Filters) > 0 {
+			for _, filter := range equipment.Filters {
+				folders = append(folders, folder+"/"+safeFolderPart(filter))
+			}
+			continue
+		}
+		folders = append(folders, folder)
+	}
+	return folders
+}
+
+// rejectedFolders returns the folders of the Rejected mirror: the frame folders of the layout
+func (c userConfig) rejectedFolders(equipment *equipmentProfile) []string {
+	var folders []string
+	for _, folder := range c.sessionFolders(equipment) {
+		if isFrameFolder(folder) {
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// createSessionTree creates the capture folders of a session and its Rejected mirror
+func (c userConfig) createSessionTree(capturePath, rejectedBase string, equipment *equipmentProfile) error {
+	if units, tooLong := pathUnits(rejectedBase); tooLong {
+		trPrintf("⚠️  %s is %d characters long; Windows tools and SMB shares may fail on frames over %d characters. A shorter target name or layout avoids it.\n", rejectedBase, units, maxPathUnits)
+	}
+	for _, folder := range c.sessionFolders(equipment) {
+		if err := os.MkdirAll(filepath.Join(capturePath, filepath.FromSlash(folder)), 0755); err != nil {
+			return fmt.Errorf("capture subfolder %s: %v", folder, err)
+		}
+	}
+	for _, folder := range c.rejectedFolders(equipment) {
+		if err := os.MkdirAll(filepath.Join(rejectedBase, filepath.FromSlash(folder)), 0755); err != nil {
+			return fmt.Errorf("rejected subfolder %s: %v", folder, err)
+		}
+	}
+	return nil
+}
+
+// isFrameFolder reports whether a session-relative folder holds frames
+func isFrameFolder(rel string) bool {
+	top := strings.Split(filepath.ToSlash(rel), "/")[0]
+	for _, f := range nonFrameFolders {
+		if strings.EqualFold(top, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func isFilterSplitFolder(folder string) bool {
+	for _, f := range filterSplitFolders {
+		if strings.EqualFold(folder, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// topLevelFolders returns the first path segment of each folder, without duplicates
+func topLevelFolders(folders []string) []string {
+	var tops []string
+	for _, f := range folders {
+		tops = appendUnique(tops, strings.Split(filepath.ToSlash(f), "/")[0])
+	}
+	return tops
+}
+
+// captureFrameFolders lists the frame folders that exist inside a capture path,
+// relative to it (e.g. "Lights", "Lights/Ha", "Flats")
+func captureFrameFolders(capturePath string) []string {
+	var folders []string
+	filepath.WalkDir(capturePath, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == capturePath {
+			return nil
+		}
+		rel, _ := filepath.Rel(capturePath, path)
+		if strings.HasPrefix(d.Name(), ".") || !isFrameFolder(rel) || strings.EqualFold(rel, calibrationFolder) {
+			return filepath.SkipDir
+		}
+		folders = append(folders, filepath.ToSlash(rel))
+		return nil
+	})
+	return folders
+}
+
+// missingMirrorFolders returns the frame folders of a capture path that its Rejected mirror lacks
+func missingMirrorFolders(capturePath, mirrorPath string) []string {
+	var missing []string
+	for _, rel := range captureFrameFolders(capturePath) {
+		if info, err := os.Stat(filepath.Join(mirrorPath, filepath.FromSlash(rel))); err != nil || !info.IsDir() {
+			missing = append(missing, rel)
+		}
+	}
+	return missing
+}
+
+// syncMirrorFolders creates the frame folders of a capture path that are missing in its mirror
+func syncMirrorFolders(capturePath, mirrorPath string) error {
+	for _, rel := range missingMirrorFolders(capturePath, mirrorPath) {
+		if err := os.MkdirAll(filepath.Join(mirrorPath, filepath.FromSlash(rel)), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
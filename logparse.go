@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// frameLogEntry holds the per-frame values a capture program wrote in its logs.
+// Missing values are NaN.
+type frameLogEntry struct {
+	File       string // base name of the frame
+	Stars      float64
+	HFR        float64
+	GuidingRMS float64 // total RMS in arcsec
+	Source     string  // "nina", "asiair", "sgp", "csv" or "log"
+}
+
+func newFrameLogEntry(source string) frameLogEntry {
+	return frameLogEntry{Stars: math.NaN(), HFR: math.NaN(), GuidingRMS: math.NaN(), Source: source}
+}
+
+// CSV column names (lowercase, alphanumeric only) understood for each value.
+// They cover NINA's ImageMetaData.csv, SGP and ASIAIR exports.
+var logColumnAliases = map[string][]string{
+	"file":  {"filepath", "filename", "file", "frame", "image", "imagename", "name"},
+	"stars": {"detectedstars", "stars", "starcount", "numstars", "nstars"},
+	"hfr":   {"hfr", "meanhfr", "avghfr", "hfrmean"},
+	"rms":   {"guidingrmsarcsec", "totalrmsarcsec", "rmsarcsec", "guidingrms", "totalrms", "guiderms", "rms"},
+}
+
+// Patterns for free-text logs (ASIAIR autorun logs, SGP logs) that mention a
+// saved frame and its values on the same line
+var (
+	reLogFrame = regexp.MustCompile(`(?i)([^\s"'\\/:]+\.(?:fits?|fts|xisf|cr2|cr3|nef|arw|tiff?|png))`)
+	reLogHFR   = regexp.MustCompile(`(?i)\bHFR\b[\s:=]*([\d.]+)`)
+	reLogStars = regexp.MustCompile(`(?i)\bstars?\b[\s:=]*(\d+)`)
+	reLogNStar = regexp.MustCompile(`(?i)(?:^|[^\d.])(\d+)\s*stars?\b`) // "245 stars"
+	reLogRMS   = regexp.MustCompile(`(?i)\b(?:total\s+)?RMS\b[^\d\n]{0,12}([\d.]+)`)
+	// "RMS: RA 0.45 Dec 0.38 Tot 0.59" (PHD2, NINA) lists the axes before the total
+	reLogRMSTotal = regexp.MustCompile(`(?i)\bRMS\b.*?\bTot(?:al)?\b[^\d\n]{0,12}([\d.]+)`)
+)
+
+// parseCaptureLogs parses every CSV and text log found under logsDir
+func parseCaptureLogs(logsDir string) []frameLogEntry {
+	var entries []frameLogEntry
+	filepath.WalkDir(logsDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		// PHD2 guide logs hold guiding samples, not per-frame values
+		if strings.HasPrefix(d.Name(), "PHD2_") {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			entries = append(entries, parseLogCSV(f, logSourceName(d.Name()))...)
+		case ".txt", ".log":
+			entries = append(entries, parseLogText(f, logSourceName(d.Name()))...)
+		}
+		return nil
+	})
+	return entries
+}
+
+// logSourceName guesses which program wrote a log from its file name
+func logSourceName(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.Contains(lower, "imagemetadata"):
+		return "nina"
+	case strings.Contains(lower, "autorun") || strings.Contains(lower, "asiair"):
+		return "asiair"
+	case strings.HasPrefix(lower, "sg_") || strings.Contains(lower, "sgp"):
+		return "sgp"
+	case strings.HasSuffix(lower, ".csv"):
+		return "csv"
+	}
+	return "log"
+}
+
+// parseLogCSV reads a CSV export with a header row, mapping known column names
+func parseLogCSV(r io.Reader, source string) []frameLogEntry {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		norm := normalizeName(h) // also drops a UTF-8 BOM
+		for key, aliases := range logColumnAliases {
+			for rank, alias := range aliases {
+				// Keep the most specific alias when several columns match
+				if norm == alias {
+					if prev, ok := columns[key]; !ok || rank < aliasRank(key, header[prev]) {
+						columns[key] = i
+					}
+				}
+			}
+		}
+	}
+	fileCol, ok := columns["file"]
+	if !ok {
+		return nil
+	}
+
+	var entries []frameLogEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil || fileCol >= len(record) {
+			continue
+		}
+		entry := newFrameLogEntry(source)
+		entry.File = baseNameAnyOS(record[fileCol])
+		if entry.File == "" {
+			continue
+		}
+		value := func(key string) float64 {
+			if col, ok := columns[key]; ok && col < len(record) {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(record[col]), 64); err == nil {
+					return v
+				}
+			}
+			return math.NaN()
+		}
+		entry.Stars = value("stars")
+		entry.HFR = value("hfr")
+		entry.GuidingRMS = value("rms")
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// aliasRank returns the position of a header among the aliases of key
+func aliasRank(key, header string) int {
+	norm := normalizeName(header)
+	for i, alias := range logColumnAliases[key] {
+		if norm == alias {
+			return i
+		}
+	}
+	return len(logColumnAliases[key])
+}
+
+// parseLogText scans a free-text log for lines naming a frame with HFR, stars or RMS values
+func parseLogText(r io.Reader, source string) []frameLogEntry {
+	var entries []frameLogEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		frame := reLogFrame.FindStringSubmatch(line)
+		if frame == nil {
+			continue
+		}
+		entry := newFrameLogEntry(source)
+		entry.File = frame[1]
+		entry.HFR = logValue(line, reLogHFR)
+		entry.Stars = logValue(line, reLogStars, reLogNStar)
+		entry.GuidingRMS = logValue(line, reLogRMSTotal, reLogRMS)
+		if math.IsNaN(entry.HFR) && math.IsNaN(entry.Stars) && math.IsNaN(entry.GuidingRMS) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// logValue returns the number captured by the first pattern that matches the
+// line, NaN when none does or the number doesn't parse ("1.2.3")
+func logValue(line string, patterns ...*regexp.Regexp) float64 {
+	for _, re := range patterns {
+		if m := re.FindStringSubmatch(line); m != nil {
+			if v, err := strconv.ParseFloat(m[1], 64); err == nil {
+				return v
+			}
+			return math.NaN()
+		}
+	}
+	return math.NaN()
+}
+
+// baseNameAnyOS returns the last element of a Windows or Unix path
+func baseNameAnyOS(p string) string {
+	p = strings.TrimSpace(p)
+	if idx := strings.LastIndexAny(p, `\/`); idx >= 0 {
+		p = p[idx+1:]
+	}
+	return p
+}
+
+// mergeLogEntries combines the entries of the same frame coming from several logs
+func mergeLogEntries(entries []frameLogEntry) map[string]frameLogEntry {
+	merged := map[string]frameLogEntry{}
+	for _, e := range entries {
+		key := strings.ToLower(e.File)
+		prev, ok := merged[key]
+		if !ok {
+			merged[key] = e
+			continue
+		}
+		if math.IsNaN(prev.Stars) {
+			prev.Stars = e.Stars
+		}
+		if math.IsNaN(prev.HFR) {
+			prev.HFR = e.HFR
+		}
+		if math.IsNaN(prev.GuidingRMS) {
+			prev.GuidingRMS = e.GuidingRMS
+		}
+		if !strings.Contains(prev.Source, e.Source) {
+			prev.Source += "+" + e.Source
+		}
+		merged[key] = prev
+	}
+	return merged
+}
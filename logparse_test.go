@@ -0,0 +1,91 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseLogText(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name  string
+		line  string
+		file  string
+		stars float64
+		hfr   float64
+		rms   float64
+	}{
+		{
+			name: "asiair autorun",
+			line: `2024/03/01 22:20:31 Light_M31_300.0s_Bin1_gain100_20240301-221530_-10.0C_0001.fit saved, HFR 2.31, Stars 412, RMS 0.62"`,
+			file: "Light_M31_300.0s_Bin1_gain100_20240301-221530_-10.0C_0001.fit", stars: 412, hfr: 2.31, rms: 0.62,
+		},
+		{
+			name: "sgp",
+			line: `[03/01/24 22:20:31.512] [DEBUG] [Sequence Thread] Image saved: C:\Astro\M31\M31_300sec_1x1_Ha_-10C_frame12.fit (HFR: 2.41, Stars: 356)`,
+			file: "M31_300sec_1x1_Ha_-10C_frame12.fit", stars: 356, hfr: 2.41, rms: nan,
+		},
+		{
+			name: "nina with guiding total",
+			line: `2024-03-01T22:20:31.5123|INFO|ImageSaveController.cs|Save|75|Saved 2024-03-01_22-15-30_Ha_-10.00_300.00s_0001.fits HFR 2.12 245 stars RMS: RA 0.45 Dec 0.38 Tot 0.59`,
+			file: "2024-03-01_22-15-30_Ha_-10.00_300.00s_0001.fits", stars: 245, hfr: 2.12, rms: 0.59,
+		},
+		{
+			name: "total rms first",
+			line: `M42_Light_L_120_secs_003.fits guiding Total RMS 0.81" (RA 0.52 Dec 0.61)`,
+			file: "M42_Light_L_120_secs_003.fits", stars: nan, hfr: nan, rms: 0.81,
+		},
+		{
+			name: "unparsable value stays unset",
+			line: `Light_M31_0002.fit saved, HFR 2.1.3, Stars 300`,
+			file: "Light_M31_0002.fit", stars: 300, hfr: nan, rms: nan,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := parseLogText(strings.NewReader(tt.line), "log")
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e.File != tt.file {
+				t.Errorf("file = %q, want %q", e.File, tt.file)
+			}
+			checkMetric(t, "stars", e.Stars, tt.stars)
+			checkMetric(t, "hfr", e.HFR, tt.hfr)
+			checkMetric(t, "rms", e.GuidingRMS, tt.rms)
+		})
+	}
+}
+
+func TestParseLogCSVNINA(t *testing.T) {
+	csv := "ExposureNumber,FilePath,FilterName,ExposureStart,Duration,DetectedStars,HFR,HFRStDev,GuidingRMS,GuidingRMSArcSec,GuidingRMSRA,GuidingRMSRAArcSec\n" +
+		`1,"D:\Astro\M31\2024-03-01\LIGHT\2024-03-01_22-15-30_Ha_-10.00_300.00s_0001.fits",Ha,2024-03-01 22:15:30,300,412,2.31,0.4,0.35,0.62,0.25,0.44` + "\n" +
+		`2,"D:\Astro\M31\2024-03-01\LIGHT\2024-03-01_22-20-35_Ha_-10.00_300.00s_0002.fits",Ha,2024-03-01 22:20:35,300,,NaN,0.4,0.35,0.70,0.25,0.44` + "\n"
+	entries := parseLogCSV(strings.NewReader(csv), "nina")
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].File != "2024-03-01_22-15-30_Ha_-10.00_300.00s_0001.fits" {
+		t.Errorf("file = %q", entries[0].File)
+	}
+	checkMetric(t, "stars", entries[0].Stars, 412)
+	checkMetric(t, "hfr", entries[0].HFR, 2.31)
+	checkMetric(t, "rms", entries[0].GuidingRMS, 0.62)
+	checkMetric(t, "missing stars", entries[1].Stars, math.NaN())
+	checkMetric(t, "rms", entries[1].GuidingRMS, 0.70)
+}
+
+func checkMetric(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.IsNaN(want) {
+		if !math.IsNaN(got) {
+			t.Errorf("%s = %g, want unset", name, got)
+		}
+		return
+	}
+	if got != want {
+		t.Errorf("%s = %g, want %g", name, got, want)
+	}
+}
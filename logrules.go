@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decisions of every reject-logs run, appended in the session folder
+const logAuditFile = "log_rules_audit.csv"
+
+// logRule rejects a frame when a logged metric crosses a limit.
+//
+//	metric: "guiding_rms" (arcsec), "stars" or "hfr"
+//	op:     ">" / "<" compare with value; "drop" rejects when the metric falls
+//	        below value × the median of the frames with the same filter (e.g.
+//	        0.5 for clouds); "rise" rejects when it exceeds value × that median
+type logRule struct {
+	Metric string  `json:"metric"`
+	Op     string  `json:"op"`
+	Value  float64 `json:"value"`
+}
+
+// Rules used when astrosession.json has no "log_rules"
+var defaultLogRules = []logRule{
+	{Metric: "guiding_rms", Op: ">", Value: 1.2},
+	{Metric: "stars", Op: "drop", Value: 0.5},
+}
+
+func init() {
+	registerCommand("reject-logs", "reject-logs [-dry-run] <Night_ session folder>",
+		"Parse NINA/ASIAIR/SGP logs in Logs/, apply log_rules (guiding RMS, star count drops, HFR) and move failing frames into the Rejected mirror.",
+		runRejectLogsCommand)
+}
+
+// metric returns the value of the rule's metric for an entry
+func (r logRule) metric(e frameLogEntry) float64 {
+	switch r.Metric {
+	case "guiding_rms":
+		return e.GuidingRMS
+	case "stars":
+		return e.Stars
+	case "hfr":
+		return e.HFR
+	}
+	return math.NaN()
+}
+
+// validate checks the rule is understood
+func (r logRule) validate() error {
+	switch r.Metric {
+	case "guiding_rms", "stars", "hfr":
+	default:
+		return fmt.Errorf("unknown log rule metric '%s'", r.Metric)
+	}
+	switch r.Op {
+	case ">", "<", "drop", "rise":
+	default:
+		return fmt.Errorf("unknown log rule op '%s'", r.Op)
+	}
+	return nil
+}
+
+// evaluate returns why the entry fails the rule, or "" when it passes
+// (or the metric wasn't logged). median is the metric's median among the
+// session frames of the same folder and filter.
+func (r logRule) evaluate(e frameLogEntry, median float64) string {
+	v := r.metric(e)
+	if math.IsNaN(v) {
+		return ""
+	}
+	name := strings.ReplaceAll(r.Metric, "_", " ")
+	switch r.Op {
+	case ">":
+		if v > r.Value {
+			return fmt.Sprintf("%s %g > %g", name, v, r.Value)
+		}
+	case "<":
+		if v < r.Value {
+			return fmt.Sprintf("%s %g < %g", name, v, r.Value)
+		}
+	case "drop":
+		if median > 0 && v < r.Value*median {
+			return fmt.Sprintf("%s %g < %g%% of median %g", name, v, r.Value*100, median)
+		}
+	case "rise":
+		if median > 0 && v > r.Value*median {
+			return fmt.Sprintf("%s %g > %g× median %g", name, v, r.Value, median)
+		}
+	}
+	return ""
+}
+
+func runRejectLogsCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("reject-logs", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report, don't move anything")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: " + commands["reject-logs"].usage)
+	}
+
+	rules := cfg.LogRules
+	if len(rules) == 0 {
+		rules = defaultLogRules
+	}
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return err
+		}
+	}
+
+	sessionPath := cleanPath(fs.Arg(0))
+	logged := mergeLogEntries(parseCaptureLogs(filepath.Join(sessionPath, "Logs")))
+	if len(logged) == 0 {
+		return fmt.Errorf("no per-frame values found in the logs of '%s'", sessionPath)
+	}
+
+	// Frames of the session indexed by lowercase base name
+	frames := map[string]string{}
+	for _, sub := range []string{"Lights", "Flats"} {
+		filepath.WalkDir(filepath.Join(sessionPath, sub), func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+				frames[strings.ToLower(d.Name())] = path
+			}
+			return nil
+		})
+	}
+
+	var keys []string
+	for k := range logged {
+		if _, ok := frames[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	trPrintf("%d frames with logged values, %d of them in this session.\n", len(logged), len(keys))
+
+	// Medians per frame folder and filter: narrowband frames are compared with
+	// narrowband frames only
+	groups := map[string]string{}
+	for _, k := range keys {
+		groups[k] = frameGroup(sessionPath, frames[k])
+	}
+	medians := map[string]map[string]float64{}
+	for _, r := range rules {
+		values := map[string][]float64{}
+		for _, k := range keys {
+			if v := r.metric(logged[k]); !math.IsNaN(v) {
+				values[groups[k]] = append(values[groups[k]], v)
+			}
+		}
+		for group, v := range values {
+			if medians[group] == nil {
+				medians[group] = map[string]float64{}
+			}
+			medians[group][r.Metric] = median(v)
+		}
+	}
+
+	var rejected []rejection
+	var audit [][]string
+	now := time.Now().Format(time.RFC3339)
+	for _, k := range keys {
+		e := logged[k]
+		var reasons []string
+		for _, r := range rules {
+			if reason := r.evaluate(e, medians[groups[k]][r.Metric]); reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+
+		decision := "keep"
+		if len(reasons) > 0 {
+			decision = "reject"
+			rejected = append(rejected, rejection{Path: frames[k], Source: "logs:" + e.Source, Reason: strings.Join(reasons, "; ")})
+			fmt.Printf("❌ %s: %s\n", e.File, strings.Join(reasons, "; "))
+		}
+		audit = append(audit, []string{now, e.File, e.Source, formatMetric(e.Stars), formatMetric(e.HFR), formatMetric(e.GuidingRMS), decision, strings.Join(reasons, "; ")})
+	}
+
+	trPrintf("\n%d of %d frames fail the log rules.\n", len(rejected), len(keys))
+	if *dryRun {
+		return nil
+	}
+	if err := appendLogAudit(filepath.Join(sessionPath, logAuditFile), audit); err != nil {
+		trPrintf("⚠️  Could not write %s: %v\n", logAuditFile, err)
+	}
+	return rejectFrames(baseDir, sessionPath, rejected)
+}
+
+// formatMetric prints a logged value, leaving missing ones empty
+func formatMetric(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// frameGroup returns the folder and filter a frame is compared within ("Lights/Ha")
+func frameGroup(sessionPath, path string) string {
+	rel, _ := filepath.Rel(sessionPath, path)
+	group := strings.Split(filepath.ToSlash(rel), "/")[0]
+	if header, err := readFrameHeader(path); err == nil {
+		group += "/" + strings.ToLower(strings.TrimSpace(header.String("FILTER")))
+	}
+	return group
+}
+
+// appendLogAudit appends the decisions of a run to the audit file, writing the
+// header row when the file is new
+func appendLogAudit(path string, rows [][]string) error {
+	_, statErr := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if os.IsNotExist(statErr) {
+		w.Write([]string{"time", "file", "source", "stars", "hfr", "guiding_rms", "decision", "reason"})
+	}
+	w.WriteAll(rows)
+	return w.Error()
+}
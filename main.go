@@ -2,15 +2,14 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 )
 
 // readInput cleans terminal inputs on both Windows (\r\n) and Linux/Mac (\n)
@@ -24,198 +23,82 @@ func readInput(reader *bufio.Reader) string {
 	return strings.TrimSpace(input)
 }
 
-func main() {
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Println("==============================================")
-	fmt.Println("=== Astrophotography Session Creator ===")
-	fmt.Println("==============================================")
-
-	fmt.Print("\nCaptured object name (e.g. M81, M81 M82, NGC 4236): ")
-	targetInput := readInput(reader)
-
-	if targetInput == "" {
-		fmt.Println("You must enter a valid name.")
-		return
+// resolveBaseDir returns the folder where target folders live: the executable's
+// folder when launched with an absolute path (double click), otherwise the working directory
+func resolveBaseDir() (string, error) {
+	baseDir, err := os.Getwd()
+	if err != nil {
+		return "", err
 	}
+	executableDir := filepath.Dir(os.Args[0])
+	if filepath.IsAbs(executableDir) {
+		baseDir = executableDir
+	}
+	return baseDir, nil
+}
 
-	targets := strings.Fields(targetInput)
-	var resolvedTechNames []string
-	var commonNames []string
-	allHaveCommonName := true
-
-	fmt.Printf("\nSearching for information on '%s' in SIMBAD/Sesame...\n", targetInput)
-
-	for _, t := range targets {
-		formatted := formatTargetName(t)
-		cName, tOptions := querySesame(t)
-
-		techName := formatted
-		if len(tOptions) > 0 {
-			if len(tOptions) == 1 {
-				techName = tOptions[0]
-				fmt.Printf("-> [%s] Using primary technical designation: %s\n", t, techName)
-			} else {
-				fmt.Printf("\nMultiple catalog designations found for [%s]:\n", t)
-				for i, opt := range tOptions {
-					fmt.Printf("  %d) %s\n", i+1, opt)
-				}
-				fmt.Printf("  %d) Keep original: %s\n", len(tOptions)+1, formatted)
-				fmt.Printf("Which nomenclature do you prefer for the main folder? (1-%d) [1]: ", len(tOptions)+1)
-
-				optInput := readInput(reader)
-				if optInput == "" {
-					optInput = "1"
-				}
-				idx, err := strconv.Atoi(optInput)
-				if err == nil && idx >= 1 && idx <= len(tOptions) {
-					techName = tOptions[idx-1]
-				}
-			}
-		} else if cName == "" {
-			fmt.Printf("-> Object [%s] not found or without common name (only using '%s').\n", t, formatted)
-		}
-
-		resolvedTechNames = append(resolvedTechNames, techName)
-
-		if cName != "" {
-			commonNames = append(commonNames, cName)
-		} else {
-			allHaveCommonName = false
-		}
+func main() {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
 	}
 
-	finalTargetFolder := strings.Join(resolvedTechNames, "_")
+	equipmentFlag := flag.String("equipment", "", "equipment profile name from "+userConfigFile)
+	siteFlag := flag.String("site", "", "observing site name from "+userConfigFile)
+	panelFlag := flag.String("panel", "", "mosaic panel number of the session (e.g. 2 or 1-2)")
+	flag.Parse()
 
-	if len(commonNames) > 0 && allHaveCommonName {
-		joinedCommon := strings.Join(commonNames, " & ")
-		if len(commonNames) > 1 && !strings.HasSuffix(strings.ToLower(joinedCommon), "galaxies") {
-			// small grammatical touch for pluralizing multiple known galaxies if they aren't labeled already
-			if strings.HasSuffix(strings.ToLower(joinedCommon), "galaxy") {
-				joinedCommon = strings.TrimSuffix(joinedCommon, "Galaxy") + "Galaxies"
-			}
-		}
-		finalTargetFolder = fmt.Sprintf("%s (%s)", finalTargetFolder, joinedCommon)
-	} else if len(commonNames) > 0 && len(targets) == 1 {
-		// Only 1 target input, perfectly append its common name.
-		finalTargetFolder = fmt.Sprintf("%s (%s)", finalTargetFolder, commonNames[0])
-	} // For multiple where 1 fails, revert strict to technical
+	reader := bufio.NewReader(os.Stdin)
 
-	baseDir, err := os.Getwd()
+	baseDir, err := resolveBaseDir()
 	if err != nil {
-		fmt.Println("Error getting current directory:", err)
+		trPrintf("Error getting current directory: %v\n", err)
 		return
 	}
+	// Loaded before the first prompt: it selects the UI language
+	cfg := loadUserConfigOrDefault(baseDir)
 
-	executableDir := filepath.Dir(os.Args[0])
-	if filepath.IsAbs(executableDir) {
-		baseDir = executableDir
+	fmt.Println("==============================================")
+	fmt.Println(tr("=== Astrophotography Session Creator ==="))
+	fmt.Println("==============================================")
+
+	fmt.Print(tr("\nCaptured object name or coordinates (e.g. M81, M81 M82, NGC 4236, 05 35 17 -05 23 28): "))
+	targetInput := readInput(reader)
+
+	if targetInput == "" {
+		fmt.Println(tr("You must enter a valid name."))
+		return
 	}
 
-	normFinal := normalizeName(finalTargetFolder)
-	normTarget := normalizeName(targetInput)
-
-	var similarFolder string
-	filesInfo, err := os.ReadDir(baseDir)
-	if err == nil {
-		for _, info := range filesInfo {
-			if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
-				normFolder := normalizeName(info.Name())
-				if normFolder == normFinal || normFolder == normTarget {
-					similarFolder = info.Name()
-					break
-				}
-			}
-		}
+	// Mosaic panel suffixes ("Veil Panel 2") are not part of the target name
+	nameInput := targetInput
+	if base, _, ok := parsePanelObject(targetInput); ok {
+		nameInput = base
 	}
 
-	if similarFolder != "" && similarFolder != finalTargetFolder {
-		fmt.Println()
-		fmt.Printf("⚠️  An existing very similar folder was found: '%s'\n", similarFolder)
-		fmt.Printf("    The new standardized format is: '%s'\n", finalTargetFolder)
-		fmt.Println("\nWhat do you want to do?")
-		fmt.Println("  1) Use the existing folder as is and add the new session inside.")
-		fmt.Printf("  2) Rename the existing folder to '%s' and add the new session there.\n", finalTargetFolder)
-		fmt.Printf("  3) Ignore and create '%s' as a completely new folder.\n", finalTargetFolder)
-
-		for {
-			fmt.Print("Choose an option (1/2/3) [1]: ")
-			resp := readInput(reader)
-
-			if resp == "" || resp == "1" {
-				finalTargetFolder = similarFolder
-				fmt.Printf("-> We will operate inside: '%s'\n", finalTargetFolder)
-				break
-			} else if resp == "2" {
-				oldPath := filepath.Join(baseDir, similarFolder)
-				newPath := filepath.Join(baseDir, finalTargetFolder)
-				err := os.Rename(oldPath, newPath)
-				if err != nil {
-					fmt.Printf("-> Error renaming the folder: %v\n", err)
-					fmt.Println("-> We will operate with the original name for safety.")
-					finalTargetFolder = similarFolder
-				} else {
-					fmt.Printf("-> Folder successfully renamed to '%s'!\n", finalTargetFolder)
-				}
-				break
-			} else if resp == "3" {
-				fmt.Printf("-> We will create a new folder: '%s'\n", finalTargetFolder)
-				break
-			} else {
-				fmt.Println("Invalid option.")
-			}
+	// Coordinates ("05 35 17 -05 23 28") are turned into the nearest catalogued object
+	targetNames := strings.Fields(nameInput)
+	if ra, dec, ok := parseCoordinateInput(nameInput); ok {
+		if targetNames = chooseNearbyObject(reader, ra, dec); len(targetNames) == 0 {
+			fmt.Println(tr("You must enter a valid name."))
+			return
 		}
 	}
+	target := resolveTargetInteractive(reader, baseDir, strings.Join(targetNames, " "), targetNames, cfg.FieldNaming)
+	finalTargetFolder, targetObjects := target.Folder, target.Objects
 
-	fmt.Println("\n----------------------------------------------")
-	fmt.Println("Enter the capture date. Options:")
-	ahora := time.Now()
-	hoyStr := fmt.Sprintf("%d %s", ahora.Day(), monthNames[int(ahora.Month())])
-	esteAñoStr := fmt.Sprintf("%d", ahora.Year())
-
-	fmt.Printf(" [Empty ENTER] -> Use today: %s (Year: %s)\n", hoyStr, esteAñoStr)
-	fmt.Printf(" '12 feb'      -> Use this date (Year: %s)\n", esteAñoStr)
-	fmt.Println(" '12 feb 2025' -> Use this date and year")
-	fmt.Print("Date: ")
+	site := chooseSite(reader, cfg, *siteFlag)
 
-	finalDate := readInput(reader)
-	finalYear := esteAñoStr
-
-	if finalDate == "" {
-		finalDate = hoyStr
-	} else {
-		parts := strings.Split(finalDate, " ")
-		if len(parts) == 3 {
-			finalDate = fmt.Sprintf("%s %s", parts[0], parts[1])
-			if match, _ := regexp.MatchString(`^\d{4}$`, parts[2]); match {
-				finalYear = parts[2]
-			}
-		}
-	}
+	night, dateInput := chooseSessionDate(reader, site)
 
 	targetRoot := filepath.Join(baseDir, finalTargetFolder)
+	panel := choosePanel(reader, targetRoot, targetInput, *panelFlag)
 
-	var finalDay, finalMonth string
-	parts := strings.Split(finalDate, " ")
-	if len(parts) >= 2 {
-		finalDay = parts[0]
-		// Zero-pad single-digit days (e.g. "5" -> "05")
-		if len(finalDay) == 1 {
-			finalDay = "0" + finalDay
-		}
-		// Capitalize the first letter of the month to enforce standard nomenclature
-		rawMonth := parts[1]
-		if len(rawMonth) > 0 {
-			finalMonth = strings.ToUpper(rawMonth[:1]) + strings.ToLower(rawMonth[1:])
-		} else {
-			finalMonth = rawMonth
-		}
-	} else {
-		finalDay = "00"
-		finalMonth = "Unknown"
-	}
+	equipment := chooseEquipment(reader, cfg, *equipmentFlag)
+	tokens := cfg.nightTokens(night, equipment, site)
+	finalYear, finalMonth, finalDay := tokens.Year, tokens.Month, tokens.Day
+	sessionRelPath := expandLayout(cfg.CaptureLayout, tokens)
 
-	capturePath := filepath.Join(targetRoot, finalYear, finalMonth, "Night_"+finalDay)
+	capturePath, rejectedBase := sessionPaths(baseDir, finalTargetFolder, panel, sessionRelPath)
 
 	if _, err := os.Stat(capturePath); err == nil {
 		hasFiles := false
@@ -223,146 +106,292 @@ func main() {
 			if err != nil {
 				return err
 			}
-			if !d.IsDir() && !strings.HasPrefix(d.Name(), ".") {
+			if !d.IsDir() && !strings.HasPrefix(d.Name(), ".") && d.Name() != sessionMetadataFile {
 				hasFiles = true
-				return filepath.SkipDir // detiene el escaneo
+				return filepath.SkipDir // stops the scan
 			}
 			return nil
 		})
 
 		if hasFiles {
 			fmt.Println("\n" + strings.Repeat("=", 50))
-			fmt.Println("🚨 WARNING: EXISTING SESSION DETECTED 🚨")
+			fmt.Println(tr("🚨 WARNING: EXISTING SESSION DETECTED 🚨"))
 			fmt.Println(strings.Repeat("=", 50))
-			fmt.Printf("A capture for '%s' already exists on Night_%s of %s %s.\n", finalTargetFolder, finalDay, finalMonth, finalYear)
-			fmt.Printf("Path: %s\n", capturePath)
-			fmt.Println("In addition, the folder ALREADY CONTAINS FILES inside (photos, logs, etc).")
-			fmt.Println("Taking the same object, 2 times, on the exact same day is unusual.")
-
-			fmt.Print("\nAre you sure you want to mix new sessions on this date? (y/n) [n]: ")
-			resp := strings.ToLower(readInput(reader))
-			if resp != "y" {
-				fmt.Println("Operation canceled. (No folder was created or modified).")
-				fmt.Println("\nPress Enter to exit...")
+			trPrintf("A capture for '%s' already exists on Night_%s of %s %s.\n", finalTargetFolder, finalDay, finalMonth, finalYear)
+			trPrintf("Path: %s\n", capturePath)
+			fmt.Println(tr("In addition, the folder ALREADY CONTAINS FILES inside (photos, logs, etc)."))
+			fmt.Println(tr("Taking the same object, 2 times, on the exact same day is unusual."))
+
+			fmt.Print(tr("\nAre you sure you want to mix new sessions on this date? (y/n) [n]: "))
+			if !answerYes(readInput(reader)) {
+				fmt.Println(tr("Operation canceled. (No folder was created or modified)."))
+				fmt.Println(tr("\nPress Enter to exit..."))
 				readInput(reader)
 				return
 			}
 		}
 	}
 
-	// Create processing folders at the root (PixInsight, Final)
-	for _, folder := range processingSubfolders {
-		folderPath := filepath.Join(targetRoot, filepath.FromSlash(folder))
-		err := os.MkdirAll(folderPath, 0755)
-		if err != nil {
-			fmt.Printf("❌ Error creating processing subfolder %s: %v\n", folder, err)
-			return
-		}
+	// Create processing folders at the root (PixInsight, Final) and record the target
+	if err := prepareTargetRoot(targetRoot, targetObjects); err != nil {
+		trPrintf("❌ Error creating processing subfolder: %v\n", err)
+		return
 	}
 
-	// Create capture folders under the specific night (Lights, Flats, etc.)
-	for _, folder := range captureSubfolders {
-		folderPath := filepath.Join(capturePath, filepath.FromSlash(folder))
-		err := os.MkdirAll(folderPath, 0755)
-		if err != nil {
-			fmt.Printf("❌ Error creating capture subfolder %s: %v\n", folder, err)
-			return
-		}
+	// Create capture folders under the specific night (Lights, Flats, etc.) and the
+	// rejected mirror at baseDir level (sibling to object folders), both from the configured layout
+	sessionFolders := cfg.sessionFolders(equipment)
+	if err := cfg.createSessionTree(capturePath, rejectedBase, equipment); err != nil {
+		trPrintf("❌ Error creating session folders: %v\n", err)
+		return
 	}
 
-	// Create rejected mirror structure at baseDir level (sibling to object folders)
-	rejectedBase := filepath.Join(baseDir, "Rejected", finalTargetFolder, finalYear, finalMonth, "Night_"+finalDay)
-	for _, folder := range rejectedSubfolders {
-		folderPath := filepath.Join(rejectedBase, folder)
-		err := os.MkdirAll(folderPath, 0755)
-		if err != nil {
-			fmt.Printf("❌ Error creating rejected subfolder %s: %v\n", folder, err)
-			return
+	openSession := func(capturePath string) *sessionMetadata {
+		session := openSessionMetadata(capturePath)
+		session.Target = sessionTarget{Input: targetInput, Folder: finalTargetFolder, Objects: targetObjects}
+		session.Date = sessionDate{Input: dateInput, Year: finalYear, Month: finalMonth, Day: finalDay}
+		if equipment != nil {
+			session.Equipment = equipment
 		}
+		session.Site = site
+		session.Panel = panel
+		return session
+	}
+	session := openSession(capturePath)
+	if err := session.save(capturePath); err != nil {
+		trPrintf("⚠️  Could not write %s: %v\n", sessionMetadataFile, err)
 	}
 
-	fmt.Println("\n✅ Structure successfully generated!")
-	fmt.Printf("📁 Target Root: %s\n", targetRoot)
-	fmt.Printf("📂 Processing folders: %s\n", strings.Join(processingSubfolders, ", "))
-	fmt.Printf("📁 Capture Path: %s\n", capturePath)
-	fmt.Printf("📂 Capture folders: %s\n", strings.Join(captureSubfolders, ", "))
-	fmt.Printf("🗑️  Rejected Path: %s\n", rejectedBase)
-
-	fmt.Print("\nDo you want to MOVE your files (Lights/Flats/Logs) to these new folders? (y/n) [n]: ")
-	respMove := strings.ToLower(readInput(reader))
-	if respMove == "y" {
-		fmt.Print("\nDrag your Lights FOLDER here (or leave empty to skip): ")
-		lightsSrc := cleanPath(readInput(reader))
-
-		fmt.Print("Drag your Flats FOLDER here (or leave empty to skip): ")
-		flatsSrc := cleanPath(readInput(reader))
-
-		fmt.Print("Drag your Logs FOLDER here (or leave empty to skip): ")
-		logsSrc := cleanPath(readInput(reader))
-
-		if lightsSrc != "" || flatsSrc != "" || logsSrc != "" {
-			hasDuplicates := false
-			if lightsSrc != "" && checkDuplicates(lightsSrc, filepath.Join(capturePath, "Lights")) {
-				hasDuplicates = true
+	captureFolders := topLevelFolders(sessionFolders)
+	fmt.Println(tr("\n✅ Structure successfully generated!"))
+	trPrintf("📁 Target Root: %s\n", targetRoot)
+	trPrintf("📂 Processing folders: %s\n", strings.Join(processingSubfolders, ", "))
+	trPrintf("📁 Capture Path: %s\n", capturePath)
+	trPrintf("📂 Capture folders: %s\n", strings.Join(sessionFolders, ", "))
+	trPrintf("🗑️  Rejected Path: %s\n", rejectedBase)
+
+	trPrintf("\nDo you want to MOVE your files (%s) to these new folders? (y/n) [n]: ", strings.Join(captureFolders, "/"))
+	if answerYes(readInput(reader)) {
+		// Source folder for each capture folder, in layout order
+		sources := map[string]string{}
+		for i, folder := range captureFolders {
+			if i == 0 {
+				trPrintf("\nDrag your %s FOLDER here (or leave empty to skip): ", folder)
+			} else {
+				trPrintf("Drag your %s FOLDER here (or leave empty to skip): ", folder)
 			}
-			if flatsSrc != "" && checkDuplicates(flatsSrc, filepath.Join(capturePath, "Flats")) {
-				hasDuplicates = true
+			src := cleanPath(readInput(reader))
+			if src == "" {
+				continue
+			}
+			sources[folder] = src
+
+			if equipment == nil && strings.EqualFold(folder, "Lights") {
+				if header, err := firstFrameHeader(src); err == nil {
+					if detected := cfg.detectEquipment(header); detected != nil {
+						trPrintf("-> Equipment detected from FITS headers: %s\n", detected.Name)
+						// {equipment} and the per-filter folders depend on the profile
+						equipment = detected
+						tokens.Equipment = equipment.Name
+						newRelPath := expandLayout(cfg.CaptureLayout, tokens)
+						newCapture, newRejected := sessionPaths(baseDir, finalTargetFolder, panel, newRelPath)
+						if err := cfg.createSessionTree(newCapture, newRejected, equipment); err != nil {
+							trPrintf("❌ Error creating session folders: %v\n", err)
+							return
+						}
+						if newCapture != capturePath {
+							if len(session.Files) == 0 {
+								os.Remove(filepath.Join(capturePath, sessionMetadataFile))
+							}
+							removeEmptyFolders(capturePath, targetRoot)
+							removeEmptyFolders(rejectedBase, filepath.Join(baseDir, rejectedFolder))
+							sessionRelPath, capturePath, rejectedBase = newRelPath, newCapture, newRejected
+							trPrintf("📁 Capture Path: %s\n", capturePath)
+							trPrintf("🗑️  Rejected Path: %s\n", rejectedBase)
+						}
+						session = openSession(capturePath)
+						if err := session.save(capturePath); err != nil {
+							trPrintf("⚠️  Could not write %s: %v\n", sessionMetadataFile, err)
+						}
+					}
+				}
 			}
-			if logsSrc != "" && checkDuplicates(logsSrc, filepath.Join(capturePath, "Logs")) {
-				hasDuplicates = true
+		}
+
+		if len(sources) > 0 {
+			hasDuplicates := false
+			for _, folder := range captureFolders {
+				if src, ok := sources[folder]; ok && checkDuplicates(src, filepath.Join(capturePath, folder)) {
+					hasDuplicates = true
+				}
 			}
 
 			if hasDuplicates {
-				fmt.Print("\n⚠️  WARNING: Possible duplicates detected in destination. They will be renamed by appending _1, _2... Do you wish to continue and duplicate them? (y/n) [n]: ")
-				respDup := strings.ToLower(readInput(reader))
-				if respDup != "y" {
-					fmt.Println("File move operation canceled.")
+				fmt.Print(tr("\n⚠️  WARNING: Possible duplicates detected in destination. They will be renamed by appending _1, _2... Do you wish to continue and duplicate them? (y/n) [n]: "))
+				if !answerYes(readInput(reader)) {
+					fmt.Println(tr("File move operation canceled."))
 					goto END_MOVE
 				}
 			}
 
-			fmt.Println("\nPreparing files to move...")
+			// Check the flats against the lights before anything is moved (including what is already in the session)
+			lightFiles := listFrameFiles(filepath.Join(capturePath, "Lights"))
+			flatFiles := listFrameFiles(filepath.Join(capturePath, "Flats"))
+			if src, ok := sources["Lights"]; ok {
+				lightFiles = append(lightFiles, frameFilesToMove(src, isFrameFile)...)
+			}
+			if src, ok := sources["Flats"]; ok {
+				flatFiles = append(flatFiles, frameFilesToMove(src, isFrameFile)...)
+			}
+			fmt.Println()
+			if printFlatValidation(readSetups(lightFiles), readSetups(flatFiles), cfg.Flats) > 0 {
+				fmt.Print(tr("Do you want to move the files anyway? (y/n) [y]: "))
+				if answerNo(readInput(reader)) {
+					fmt.Println(tr("File move operation canceled."))
+					goto END_MOVE
+				}
+			}
+
+			fmt.Println(tr("\nPreparing files to move..."))
 			var totalBytes int64
 			var movedBytes int64
 
-			if lightsSrc != "" {
-				totalBytes += calculateTotalSize(lightsSrc)
-			}
-			if flatsSrc != "" {
-				totalBytes += calculateTotalSize(flatsSrc)
-			}
-			if logsSrc != "" {
-				totalBytes += calculateTotalSize(logsSrc)
+			for _, src := range sources {
+				totalBytes += calculateTotalSize(src)
 			}
 
-			fmt.Println("Starting transfer...")
+			fmt.Println(tr("Starting transfer..."))
 			var wg sync.WaitGroup
+			var moved moveLog
 
 			doneChan := make(chan bool)
 			go printProgressBar(&totalBytes, &movedBytes, doneChan)
 
-			if lightsSrc != "" {
-				wg.Add(1)
-				go moveFiles(lightsSrc, filepath.Join(capturePath, "Lights"), &wg, &movedBytes)
-			}
-			if flatsSrc != "" {
-				wg.Add(1)
-				go moveFiles(flatsSrc, filepath.Join(capturePath, "Flats"), &wg, &movedBytes)
-			}
-			if logsSrc != "" {
-				wg.Add(1)
-				go moveFiles(logsSrc, filepath.Join(capturePath, "Logs"), &wg, &movedBytes)
+			for _, folder := range captureFolders {
+				if src, ok := sources[folder]; ok {
+					perFilter := cfg.PerFilterFolders && isFilterSplitFolder(folder)
+					wg.Add(1)
+					go moveFiles(src, filepath.Join(capturePath, folder), perFilter, &wg, &movedBytes, &moved)
+				}
 			}
 
 			wg.Wait()
 			doneChan <- true
 
 			fmt.Printf("\rProgress: [==================================================] 100%% | ETA: 0s          \n")
-			fmt.Println("\nMove process completed!")
+			fmt.Println(tr("\nMove process completed!"))
+
+			printFlatValidation(
+				readSetups(listFrameFiles(filepath.Join(capturePath, "Lights"))),
+				readSetups(listFrameFiles(filepath.Join(capturePath, "Flats"))),
+				cfg.Flats)
+
+			// Filter folders created while moving need their Rejected counterpart too
+			if err := syncMirrorFolders(capturePath, rejectedBase); err != nil {
+				trPrintf("⚠️  Could not update the Rejected mirror: %v\n", err)
+			}
+
+			session.addFiles(capturePath, moved.files)
+			if err := session.save(capturePath); err != nil {
+				trPrintf("⚠️  Could not update %s: %v\n", sessionMetadataFile, err)
+			}
 		}
 	}
 
 END_MOVE:
-	fmt.Println("\nPress Enter to exit...")
+	fmt.Println(tr("\nPress Enter to exit..."))
 	readInput(reader)
 }
+
+// chooseEquipment selects the equipment profile for the session from the flag,
+// a prompt (by number, name, or a dragged light frame/folder) or the default
+func chooseEquipment(reader *bufio.Reader, cfg userConfig, flagValue string) *equipmentProfile {
+	if flagValue != "" {
+		if p := cfg.findEquipment(flagValue); p != nil {
+			trPrintf("-> Equipment profile: %s\n", p.Name)
+			return p
+		}
+		trPrintf("⚠️  Equipment profile '%s' not found in %s.\n", flagValue, userConfigFile)
+	}
+	if len(cfg.Equipment) == 0 {
+		return nil
+	}
+
+	fmt.Println(tr("\nEquipment profiles:"))
+	for i, p := range cfg.Equipment {
+		fmt.Printf("  %d) %s", i+1, p.Name)
+		if p.Telescope != "" || p.Camera != "" {
+			fmt.Printf(" (%s)", strings.Trim(p.Telescope+" + "+p.Camera, " +"))
+		}
+		fmt.Println()
+	}
+	defaultLabel := tr("none")
+	if cfg.DefaultEquipment != "" {
+		defaultLabel = cfg.DefaultEquipment
+	}
+
+	for {
+		trPrintf("Choose a profile, or drag a light frame/folder to auto-detect [%s]: ", defaultLabel)
+		resp := cleanPath(readInput(reader))
+		if resp == "" {
+			return cfg.findEquipment(cfg.DefaultEquipment)
+		}
+		if idx, err := strconv.Atoi(resp); err == nil && idx >= 1 && idx <= len(cfg.Equipment) {
+			return &cfg.Equipment[idx-1]
+		}
+		if p := cfg.findEquipment(resp); p != nil {
+			return p
+		}
+		if header, err := firstFrameHeader(resp); err == nil {
+			if p := cfg.detectEquipment(header); p != nil {
+				trPrintf("-> Equipment detected from FITS headers: %s\n", p.Name)
+				return p
+			}
+			trPrintf("-> No profile matches TELESCOP='%s' INSTRUME='%s'.\n", header.String("TELESCOP"), header.String("INSTRUME"))
+			continue
+		}
+		fmt.Println(tr("Invalid option."))
+	}
+}
+
+// chooseSite selects the observing site from the flag, a prompt or the default
+func chooseSite(reader *bufio.Reader, cfg userConfig, flagValue string) *siteProfile {
+	if flagValue != "" {
+		if s := cfg.findSite(flagValue); s != nil {
+			trPrintf("-> Observing site: %s\n", s.Name)
+			return s
+		}
+		trPrintf("⚠️  Site '%s' not found in %s.\n", flagValue, userConfigFile)
+	}
+	if len(cfg.Sites) == 0 {
+		return nil
+	}
+
+	fmt.Println(tr("\nObserving sites:"))
+	for i, s := range cfg.Sites {
+		fmt.Printf("  %d) %s", i+1, s.Name)
+		if s.Timezone != "" {
+			fmt.Printf(" (%s)", s.Timezone)
+		}
+		fmt.Println()
+	}
+	defaultLabel := tr("local time")
+	if cfg.DefaultSite != "" {
+		defaultLabel = cfg.DefaultSite
+	}
+
+	for {
+		trPrintf("Choose a site [%s]: ", defaultLabel)
+		resp := readInput(reader)
+		if resp == "" {
+			return cfg.findSite(cfg.DefaultSite)
+		}
+		if idx, err := strconv.Atoi(resp); err == nil && idx >= 1 && idx <= len(cfg.Sites) {
+			return &cfg.Sites[idx-1]
+		}
+		if s := cfg.findSite(resp); s != nil {
+			return s
+		}
+		fmt.Println(tr("Invalid option."))
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommand("migrate-layout", "migrate-layout [-dry-run]",
+		"Rename the {month} folders of existing sessions and their Rejected mirrors to the month_folders style (Feb -> 02-Feb or 02), updating session.json.",
+		runMigrateLayoutCommand)
+}
+
+// monthRename is a {month} folder to rename in place
+type monthRename struct {
+	From string
+	To   string
+}
+
+func runMigrateLayoutCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("migrate-layout", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only show the folders that would be renamed")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["migrate-layout"].usage)
+	}
+
+	if !validMonthFolders(cfg.MonthFolders) {
+		return fmt.Errorf("unknown month_folders '%s' in %s (use %s, %s or %s)", cfg.MonthFolders, userConfigFile,
+			monthFoldersName, monthFoldersNumberName, monthFoldersNumber)
+	}
+	depths, err := monthFolderDepths(cfg.CaptureLayout)
+	if err != nil {
+		return err
+	}
+
+	var renames []monthRename
+	var conflicts []string
+	planned := map[string]string{}
+	for _, root := range layoutRoots(baseDir) {
+		for _, dir := range foldersAtDepths(root, depths) {
+			m, ok := parseMonthFolder(filepath.Base(dir))
+			if !ok {
+				continue
+			}
+			to := filepath.Join(filepath.Dir(dir), cfg.monthFolder(m))
+			if to == dir {
+				continue
+			}
+			rel, _ := filepath.Rel(baseDir, dir)
+			if other, ok := planned[to]; ok {
+				// "Oct" and "10-Oct" side by side both become "10"
+				conflicts = append(conflicts, tr("%s (%s also becomes %s)", filepath.ToSlash(rel), filepath.Base(other), filepath.Base(to)))
+				continue
+			}
+			if existing, err := os.Stat(to); err == nil {
+				// "feb" -> "Feb" on a case-insensitive disk is the same folder
+				if current, err := os.Stat(dir); err != nil || !os.SameFile(existing, current) {
+					conflicts = append(conflicts, tr("%s (%s already exists)", filepath.ToSlash(rel), filepath.Base(to)))
+					continue
+				}
+			}
+			planned[to] = dir
+			renames = append(renames, monthRename{From: dir, To: to})
+		}
+	}
+
+	for _, c := range conflicts {
+		trPrintf("⚠️  Skipped %s; merge the two folders by hand\n", c)
+	}
+	if len(renames) == 0 {
+		fmt.Println(tr("✅ Every month folder already follows the layout."))
+		return nil
+	}
+
+	trPrintf("\n📋 %d month folders to rename:\n", len(renames))
+	for _, r := range renames {
+		rel, _ := filepath.Rel(baseDir, r.From)
+		fmt.Printf("  📁 %s -> %s\n", filepath.ToSlash(rel), filepath.Base(r.To))
+	}
+	if *dryRun {
+		return nil
+	}
+	fmt.Print(tr("\nRename the folders? (y/n) [y]: "))
+	if answerNo(readInput(bufio.NewReader(os.Stdin))) {
+		fmt.Println(tr("Operation canceled."))
+		return nil
+	}
+
+	renamed, updated := 0, 0
+	for _, r := range renames {
+		if err := os.Rename(r.From, r.To); err != nil {
+			fmt.Printf("❌ %s: %v\n", r.From, err)
+			continue
+		}
+		renamed++
+		updated += updateSessionMonths(r.To, filepath.Base(r.From), filepath.Base(r.To))
+	}
+	trPrintf("\n✅ %d folders renamed, %d session.json files updated.\n", renamed, updated)
+	if renamed < len(renames) || len(conflicts) > 0 {
+		fmt.Println(tr("💡 Run verify-layout to check the Rejected mirrors."))
+	}
+	return nil
+}
+
+// monthFolderDepths returns how deep below a target root the {month} folders of a
+// layout are. Segments made only of {equipment} or {site} are dropped when the
+// profile is missing, so the month folder may also sit one level higher for each.
+func monthFolderDepths(layout string) ([]int, error) {
+	depths := []int{1}
+	for _, seg := range strings.Split(filepath.ToSlash(layout), "/") {
+		if seg == "{month}" {
+			return depths, nil
+		}
+		if strings.Contains(seg, "{month}") {
+			return nil, fmt.Errorf("capture_layout '%s' mixes {month} with other text in a folder; only a {month} folder of its own can be migrated", layout)
+		}
+		optional := strings.Trim(strings.NewReplacer("{equipment}", "", "{site}", "").Replace(seg), "_- ") == ""
+		for i := range depths {
+			depths[i]++
+		}
+		if optional {
+			depths = append(depths, depths[len(depths)-1]-1)
+		}
+	}
+	return nil, fmt.Errorf("capture_layout '%s' has no {month} folder", layout)
+}
+
+// layoutRoots returns the folders the capture layout starts from: every target,
+// its Panel_ folders and their Rejected mirrors
+func layoutRoots(baseDir string) []string {
+	var targets []string
+	for _, parent := range []string{baseDir, filepath.Join(baseDir, rejectedFolder)} {
+		entries, err := os.ReadDir(parent)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			if parent == baseDir && (e.Name() == rejectedFolder || e.Name() == calibrationFolder) {
+				continue
+			}
+			targets = append(targets, filepath.Join(parent, e.Name()))
+		}
+	}
+
+	var roots []string
+	for _, target := range targets {
+		roots = append(roots, target)
+		for _, panel := range listPanels(target) {
+			roots = append(roots, filepath.Join(target, panel))
+		}
+	}
+	return roots
+}
+
+// foldersAtDepths returns the folders found the given number of levels below a
+// layout root, leaving out hidden, processing and Panel_ folders
+func foldersAtDepths(root string, depths []int) []string {
+	maxDepth := 0
+	wanted := map[int]bool{}
+	for _, d := range depths {
+		wanted[d] = true
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+
+	var found []string
+	level := []string{root}
+	for depth := 1; depth <= maxDepth && len(level) > 0; depth++ {
+		var next []string
+		for _, dir := range level {
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				name := e.Name()
+				if !e.IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(name, panelFolderPrefix) || isProcessingFolder(name) {
+					continue
+				}
+				next = append(next, filepath.Join(dir, name))
+			}
+		}
+		if wanted[depth] {
+			found = append(found, next...)
+		}
+		level = next
+	}
+	sort.Strings(found)
+	return found
+}
+
+// isProcessingFolder reports whether a target subfolder is one of the processing folders
+func isProcessingFolder(name string) bool {
+	for _, f := range processingSubfolders {
+		if strings.EqualFold(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSessionMonths rewrites the month of the session.json files below a renamed
+// month folder, returning how many were updated
+func updateSessionMonths(monthDir, from, to string) int {
+	updated := 0
+	sessions := findSessionFolders(monthDir, false)
+	if _, err := os.Stat(filepath.Join(monthDir, sessionMetadataFile)); err == nil {
+		// Layouts ending in {month} keep the session in the month folder itself
+		sessions = append(sessions, monthDir)
+	}
+	for _, session := range sessions {
+		meta, err := loadSessionMetadata(session)
+		if err != nil || meta == nil || meta.Date.Month != from {
+			continue
+		}
+		meta.Date.Month = to
+		if err := meta.save(session); err != nil {
+			fmt.Printf("⚠️  %s: %v\n", session, err)
+			continue
+		}
+		updated++
+	}
+	return updated
+}
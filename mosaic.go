@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Prefix of the panel folders of a mosaic target (Panel_01, Panel_02...)
+const panelFolderPrefix = "Panel_"
+
+// Panel suffixes written by capture programs in OBJECT or target names:
+// NINA "Veil Panel 2" / "M31_Panel_3", ASIAIR "M31-1-2" (row-column) or "M31_P2"
+var (
+	rePanelWord   = regexp.MustCompile(`(?i)^(.*?)[\s_-]*(?:panel|pane|tile)[\s_-]*(\d+)$`)
+	rePanelShort  = regexp.MustCompile(`(?i)^(.+?)[\s_-]+P(\d+)$`)
+	rePanelRowCol = regexp.MustCompile(`^(.+?)[\s_]*-(\d{1,2})-(\d{1,2})$`)
+)
+
+// parsePanelObject splits a mosaic panel name into the target and the panel id
+// ("02", or "01-02" for row-column grids). ok is false for regular targets.
+func parsePanelObject(object string) (base, panel string, ok bool) {
+	object = strings.TrimSpace(object)
+	if m := rePanelWord.FindStringSubmatch(object); m != nil && strings.TrimSpace(m[1]) != "" {
+		return strings.TrimSpace(m[1]), padPanelNumber(m[2]), true
+	}
+	if m := rePanelShort.FindStringSubmatch(object); m != nil {
+		return strings.TrimSpace(m[1]), padPanelNumber(m[2]), true
+	}
+	if m := rePanelRowCol.FindStringSubmatch(object); m != nil {
+		return strings.TrimSpace(m[1]), padPanelNumber(m[2]) + "-" + padPanelNumber(m[3]), true
+	}
+	return object, "", false
+}
+
+// padPanelNumber zero-pads a panel number so the folders sort ("2" -> "02")
+func padPanelNumber(n string) string {
+	v, err := strconv.Atoi(n)
+	if err != nil {
+		return n
+	}
+	return fmt.Sprintf("%02d", v)
+}
+
+// panelFolder returns the folder of a panel ("Panel_02"), or "" when not a mosaic
+func panelFolder(panel string) string {
+	if panel == "" {
+		return ""
+	}
+	return panelFolderPrefix + panel
+}
+
+// sessionPaths returns the capture path of a session and its Rejected mirror.
+// Mosaic panels get their own tree inside the target root.
+func sessionPaths(baseDir, targetFolder, panel, sessionRelPath string) (capturePath, rejectedBase string) {
+	rel := filepath.Join(targetFolder, panelFolder(panel), sessionRelPath)
+	return filepath.Join(baseDir, rel), filepath.Join(baseDir, rejectedFolder, rel)
+}
+
+// listPanels returns the panel folders already present in a target root
+func listPanels(targetRoot string) []string {
+	entries, err := os.ReadDir(targetRoot)
+	if err != nil {
+		return nil
+	}
+	var panels []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), panelFolderPrefix) {
+			panels = append(panels, e.Name())
+		}
+	}
+	sort.Strings(panels)
+	return panels
+}
+
+// normalizePanel turns a typed panel ("2", "Panel_2", "1-2") into a panel id
+func normalizePanel(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= len(panelFolderPrefix) && strings.EqualFold(s[:len(panelFolderPrefix)], panelFolderPrefix) {
+		s = s[len(panelFolderPrefix):]
+	}
+	parts := strings.Split(s, "-")
+	for i, p := range parts {
+		parts[i] = padPanelNumber(strings.TrimSpace(p))
+	}
+	return safeFolderPart(strings.Join(parts, "-"))
+}
+
+// choosePanel returns the mosaic panel of the session: the flag value, a panel
+// suffix in what the user typed, or a prompt when the target is already a mosaic
+func choosePanel(reader *bufio.Reader, targetRoot, input, flagValue string) string {
+	if flagValue != "" {
+		return normalizePanel(flagValue)
+	}
+	if _, panel, ok := parsePanelObject(input); ok {
+		trPrintf("-> Mosaic panel: %s\n", panel)
+		return panel
+	}
+	panels := listPanels(targetRoot)
+	if len(panels) == 0 {
+		return ""
+	}
+
+	trPrintf("\nThis target is a mosaic (%s).\n", strings.Join(panels, ", "))
+	fmt.Print(tr("Panel number of this session (empty for none): "))
+	return normalizePanel(readInput(reader))
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParsePanelObject(t *testing.T) {
+	tests := []struct {
+		object string
+		base   string
+		panel  string
+		ok     bool
+	}{
+		{"Veil Panel 2", "Veil", "02", true},
+		{"M31_Panel_3", "M31", "03", true},
+		{"NGC 7000 Tile 12", "NGC 7000", "12", true},
+		{"M31_P2", "M31", "02", true},
+		{"M31 P2", "M31", "02", true},
+		{"IC 1396 P2", "IC 1396", "02", true},
+		{"Veil_p4", "Veil", "04", true},
+		{"M31-1-2", "M31", "01-02", true},
+		{"NGC 6992-1-2", "NGC 6992", "01-02", true},
+		{"Heart-2-10", "Heart", "02-10", true},
+		{"M31", "M31", "", false},
+		{"NGC 6992", "NGC 6992", "", false},
+		{"Sh2-155", "Sh2-155", "", false},
+		{"Panel 2", "Panel 2", "", false},
+		{"P2", "P2", "", false},
+	}
+	for _, tt := range tests {
+		base, panel, ok := parsePanelObject(tt.object)
+		if base != tt.base || panel != tt.panel || ok != tt.ok {
+			t.Errorf("parsePanelObject(%q) = %q, %q, %v; want %q, %q, %v", tt.object, base, panel, ok, tt.base, tt.panel, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outputs of guide-report, written into the session folder
+const (
+	guideSummaryFile = "guide_summary.json"
+	guideReportFile  = "guide_report.txt"
+)
+
+// PHD2 writes local times in this layout
+const phd2TimeLayout = "2006-01-02 15:04:05"
+
+var rePHD2PixelScale = regexp.MustCompile(`Pixel scale = ([\d.]+) arc-sec/px`)
+
+// guideSample is one guide camera frame
+type guideSample struct {
+	Time    time.Time
+	RA, Dec float64 // raw distances in guide pixels
+	Dropped bool
+}
+
+// guideSection is one "Guiding Begins" ... "Guiding Ends" block of a PHD2 log
+type guideSection struct {
+	Start      time.Time
+	End        time.Time
+	PixelScale float64
+	Samples    []guideSample
+	StarLost   []time.Time
+	Dithers    []time.Time
+	Settles    []float64 // seconds from "Settling started" to "Settling complete"
+}
+
+// guideSummary is the content of guide_summary.json
+type guideSummary struct {
+	Logs        []string              `json:"logs"`
+	PixelScale  float64               `json:"guide_pixel_scale_arcsec"`
+	ImageScale  float64               `json:"image_pixel_scale_arcsec,omitempty"`
+	Sections    []guideSectionSummary `json:"sections"`
+	Frames      []guideFrameSummary   `json:"frames,omitempty"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// guideRMS is the guiding error of a set of samples
+type guideRMS struct {
+	RAPixels       float64 `json:"ra_px"`
+	DecPixels      float64 `json:"dec_px"`
+	TotalPixels    float64 `json:"total_px"`
+	RAArcsec       float64 `json:"ra_arcsec"`
+	DecArcsec      float64 `json:"dec_arcsec"`
+	TotalArcsec    float64 `json:"total_arcsec"`
+	TotalImagingPx float64 `json:"total_imaging_px,omitempty"`
+}
+
+type guideSectionSummary struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Samples       int       `json:"samples"`
+	RMS           guideRMS  `json:"rms"`
+	StarLost      int       `json:"star_lost"`
+	Dithers       int       `json:"dithers"`
+	SettleMedianS float64   `json:"settle_median_s,omitempty"`
+	SettleMaxS    float64   `json:"settle_max_s,omitempty"`
+}
+
+type guideFrameSummary struct {
+	File      string    `json:"file"`
+	Start     time.Time `json:"start"`
+	ExposureS float64   `json:"exposure_s"`
+	Samples   int       `json:"samples"`
+	RMS       *guideRMS `json:"rms,omitempty"`
+	StarLost  int       `json:"star_lost"`
+	Dithered  bool      `json:"dithered"`
+}
+
+func init() {
+	registerCommand("guide-report", "guide-report <Night_ session folder>",
+		"Analyze the PHD2_GuideLog files in Logs/ (RA/Dec RMS, star lost, dither settling), correlate them with the lights and write guide_summary.json and guide_report.txt.",
+		runGuideReportCommand)
+}
+
+// parsePHD2Log splits a PHD2 guide log into its guiding sections. Times are
+// interpreted in loc, the time zone of the capture computer.
+func parsePHD2Log(path string, loc *time.Location) ([]guideSection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sections []guideSection
+	var current *guideSection
+	var columns map[string]int
+	var lastTime time.Time
+	var settleStart time.Time
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "Guiding Begins at "):
+			start, err := time.ParseInLocation(phd2TimeLayout, strings.TrimPrefix(line, "Guiding Begins at "), loc)
+			if err != nil {
+				current = nil
+				continue
+			}
+			sections = append(sections, guideSection{Start: start})
+			current = &sections[len(sections)-1]
+			columns = nil
+			lastTime = start
+
+		case current == nil:
+			continue
+
+		case strings.HasPrefix(line, "Guiding Ends at "):
+			if end, err := time.ParseInLocation(phd2TimeLayout, strings.TrimPrefix(line, "Guiding Ends at "), loc); err == nil {
+				current.End = end
+			}
+			current = nil
+
+		case rePHD2PixelScale.MatchString(line):
+			m := rePHD2PixelScale.FindStringSubmatch(line)
+			current.PixelScale, _ = strconv.ParseFloat(m[1], 64)
+
+		case strings.HasPrefix(line, "Frame,Time,"):
+			columns = map[string]int{}
+			for i, name := range strings.Split(line, ",") {
+				columns[name] = i
+			}
+
+		case strings.HasPrefix(line, "INFO:"):
+			upper := strings.ToUpper(line)
+			switch {
+			case strings.Contains(upper, "DITHER"):
+				current.Dithers = append(current.Dithers, lastTime)
+			case strings.Contains(upper, "SETTLING STARTED"):
+				settleStart = lastTime
+			case strings.Contains(upper, "SETTLING COMPLETE") && !settleStart.IsZero():
+				current.Settles = append(current.Settles, lastTime.Sub(settleStart).Seconds())
+				settleStart = time.Time{}
+			}
+
+		case columns != nil && len(line) > 0 && line[0] >= '0' && line[0] <= '9':
+			fields := strings.Split(line, ",")
+			field := func(name string) string {
+				if i, ok := columns[name]; ok && i < len(fields) {
+					return strings.Trim(fields[i], `" `)
+				}
+				return ""
+			}
+			secs, err := strconv.ParseFloat(field("Time"), 64)
+			if err != nil {
+				continue
+			}
+			sample := guideSample{Time: current.Start.Add(time.Duration(secs * float64(time.Second)))}
+			lastTime = sample.Time
+
+			ra, errRA := strconv.ParseFloat(field("RARawDistance"), 64)
+			dec, errDec := strconv.ParseFloat(field("DECRawDistance"), 64)
+			errorCode := field("ErrorCode")
+			if field("mount") == "DROP" || errRA != nil || errDec != nil {
+				sample.Dropped = true
+				if strings.Contains(strings.ToLower(line), "star lost") || (errorCode != "" && errorCode != "0") {
+					current.StarLost = append(current.StarLost, sample.Time)
+				}
+			} else {
+				sample.RA, sample.Dec = ra, dec
+			}
+			current.Samples = append(current.Samples, sample)
+		}
+	}
+	return sections, scanner.Err()
+}
+
+// computeGuideRMS returns the standard deviation of the guided samples between from and to
+// (zero times mean unbounded), converted with the guide and imaging scales when known
+func computeGuideRMS(samples []guideSample, from, to time.Time, guideScale, imageScale float64) (guideRMS, int) {
+	var ra, dec []float64
+	for _, s := range samples {
+		if s.Dropped || (!from.IsZero() && s.Time.Before(from)) || (!to.IsZero() && s.Time.After(to)) {
+			continue
+		}
+		ra = append(ra, s.RA)
+		dec = append(dec, s.Dec)
+	}
+	if len(ra) == 0 {
+		return guideRMS{}, 0
+	}
+
+	r := guideRMS{RAPixels: stdDev(ra), DecPixels: stdDev(dec)}
+	r.TotalPixels = math.Hypot(r.RAPixels, r.DecPixels)
+	if guideScale > 0 {
+		r.RAArcsec = r.RAPixels * guideScale
+		r.DecArcsec = r.DecPixels * guideScale
+		r.TotalArcsec = r.TotalPixels * guideScale
+		if imageScale > 0 {
+			r.TotalImagingPx = r.TotalArcsec / imageScale
+		}
+	}
+	return r, len(ra)
+}
+
+func stdDev(values []float64) float64 {
+	var sum, sumSq float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	for _, v := range values {
+		sumSq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sumSq / float64(len(values)))
+}
+
+func countBetween(times []time.Time, from, to time.Time) int {
+	n := 0
+	for _, t := range times {
+		if !t.Before(from) && !t.After(to) {
+			n++
+		}
+	}
+	return n
+}
+
+func runGuideReportCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["guide-report"].usage)
+	}
+	sessionPath := cleanPath(args[0])
+
+	// The session sidecar knows the site (capture computer time zone) and equipment
+	loc := time.Local
+	var equipment *equipmentProfile
+	if meta, err := loadSessionMetadata(sessionPath); err == nil && meta != nil {
+		loc = meta.Site.location()
+		equipment = meta.Equipment
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(sessionPath, "Logs", "PHD2_GuideLog*.txt"))
+	if len(logs) == 0 {
+		return fmt.Errorf("no PHD2_GuideLog files found in '%s'", filepath.Join(sessionPath, "Logs"))
+	}
+
+	summary := guideSummary{GeneratedAt: time.Now()}
+	var sections []guideSection
+	for _, path := range logs {
+		s, err := parsePHD2Log(path, loc)
+		if err != nil {
+			trPrintf("⚠️  Could not read %s: %v\n", filepath.Base(path), err)
+			continue
+		}
+		sections = append(sections, s...)
+		summary.Logs = append(summary.Logs, filepath.Base(path))
+	}
+	if len(sections) == 0 {
+		return errors.New("no guiding sections found in the PHD2 logs")
+	}
+	sort.Slice(sections, func(i, j int) bool { return sections[i].Start.Before(sections[j].Start) })
+
+	if equipment != nil {
+		summary.PixelScale = equipment.guidePixelScale()
+		summary.ImageScale = equipment.pixelScale()
+	}
+	if summary.PixelScale == 0 {
+		summary.PixelScale = sections[0].PixelScale // scale PHD2 was configured with
+	}
+
+	var allSamples []guideSample
+	var allLost, allDithers []time.Time
+	for _, s := range sections {
+		rms, n := computeGuideRMS(s.Samples, time.Time{}, time.Time{}, summary.PixelScale, summary.ImageScale)
+		ss := guideSectionSummary{Start: s.Start, End: s.End, Samples: n, RMS: rms, StarLost: len(s.StarLost), Dithers: len(s.Dithers)}
+		if len(s.Settles) > 0 {
+			settles := append([]float64(nil), s.Settles...)
+			ss.SettleMedianS = median(settles) // sorts settles
+			ss.SettleMaxS = settles[len(settles)-1]
+		}
+		summary.Sections = append(summary.Sections, ss)
+		allSamples = append(allSamples, s.Samples...)
+		allLost = append(allLost, s.StarLost...)
+		allDithers = append(allDithers, s.Dithers...)
+	}
+
+	for _, path := range listFrameFiles(filepath.Join(sessionPath, "Lights")) {
+		header, err := readFrameHeader(path)
+		if err != nil {
+			continue
+		}
+		start, ok := dateObs(header)
+		exposure, okExp := exposureTime(header)
+		if !ok || !okExp {
+			continue
+		}
+		end := start.Add(time.Duration(exposure * float64(time.Second)))
+		frame := guideFrameSummary{File: filepath.Base(path), Start: start, ExposureS: exposure}
+		rms, n := computeGuideRMS(allSamples, start, end, summary.PixelScale, summary.ImageScale)
+		frame.Samples = n
+		if n > 0 {
+			frame.RMS = &rms
+		}
+		frame.StarLost = countBetween(allLost, start, end)
+		frame.Dithered = countBetween(allDithers, start, end) > 0
+		summary.Frames = append(summary.Frames, frame)
+	}
+	sort.Slice(summary.Frames, func(i, j int) bool { return summary.Frames[i].Start.Before(summary.Frames[j].Start) })
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(sessionPath, guideSummaryFile), data, 0644); err != nil {
+		return err
+	}
+
+	report := formatGuideReport(summary)
+	fmt.Print(report)
+	if err := os.WriteFile(filepath.Join(sessionPath, guideReportFile), []byte(report), 0644); err != nil {
+		return err
+	}
+	trPrintf("\n✅ Written %s and %s\n", guideSummaryFile, guideReportFile)
+	return nil
+}
+
+// formatGuideReport renders the summary as the plain-text report
+func formatGuideReport(s guideSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PHD2 guiding report (%s)\n", strings.Join(s.Logs, ", "))
+	if s.PixelScale > 0 {
+		fmt.Fprintf(&b, "Guide scale: %.2f\"/px", s.PixelScale)
+		if s.ImageScale > 0 {
+			fmt.Fprintf(&b, " | Imaging scale: %.2f\"/px", s.ImageScale)
+		}
+		b.WriteString("\n")
+	}
+
+	for i, sec := range s.Sections {
+		fmt.Fprintf(&b, "\nSection %d: %s -> %s (%d samples)\n", i+1, sec.Start.Format(phd2TimeLayout), sec.End.Format("15:04:05"), sec.Samples)
+		fmt.Fprintf(&b, "  RMS px:     RA %.2f  Dec %.2f  Total %.2f\n", sec.RMS.RAPixels, sec.RMS.DecPixels, sec.RMS.TotalPixels)
+		if s.PixelScale > 0 {
+			fmt.Fprintf(&b, "  RMS arcsec: RA %.2f  Dec %.2f  Total %.2f\n", sec.RMS.RAArcsec, sec.RMS.DecArcsec, sec.RMS.TotalArcsec)
+		}
+		fmt.Fprintf(&b, "  Star lost: %d | Dithers: %d", sec.StarLost, sec.Dithers)
+		if sec.SettleMaxS > 0 {
+			fmt.Fprintf(&b, " | Settle median %.1fs, max %.1fs", sec.SettleMedianS, sec.SettleMaxS)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(s.Frames) > 0 {
+		fmt.Fprintf(&b, "\n%-40s %-20s %8s %8s %6s %s\n", "Light frame", "Start (UTC)", "RMS px", "RMS \"", "Lost", "Dither")
+		for _, f := range s.Frames {
+			rmsPx, rmsArc := "-", "-"
+			if f.RMS != nil {
+				rmsPx = fmt.Sprintf("%.2f", f.RMS.TotalPixels)
+				if s.PixelScale > 0 {
+					rmsArc = fmt.Sprintf("%.2f", f.RMS.TotalArcsec)
+				}
+			}
+			dither := ""
+			if f.Dithered {
+				dither = "yes"
+			}
+			fmt.Fprintf(&b, "%-40s %-20s %8s %8s %6d %s\n", f.File, f.Start.UTC().Format(phd2TimeLayout), rmsPx, rmsArc, f.StarLost, dither)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Star detection parameters (in analysis pixels, i.e. after binning)
+const (
+	starDetectSigma  = 5.0  // peak must be this many noise sigmas above the background
+	starWindowRadius = 8    // measurement aperture radius
+	maxStarsMeasured = 1000 // brightest candidates measured per frame
+	backgroundSample = 200000
+)
+
+// frameQuality holds the per-frame metrics used by cull. HFR and FWHM are in
+// original (unbinned) pixels; star metrics are 0 when no stars were found.
+type frameQuality struct {
+	Stars        int
+	HFR          float64
+	FWHM         float64
+	Eccentricity float64
+	Background   float64
+}
+
+// starMeasure is a single measured star
+type starMeasure struct {
+	hfr, fwhm, ecc float64
+}
+
+// analyzeFrame computes background median, star count, HFR, FWHM and eccentricity
+func analyzeFrame(img *fitsImage) frameQuality {
+	bg, sigma := backgroundStats(img.Data)
+	q := frameQuality{Background: bg}
+	if sigma <= 0 {
+		return q
+	}
+
+	threshold := float32(bg + starDetectSigma*sigma)
+	r := starWindowRadius
+	type peak struct {
+		x, y int
+		v    float32
+	}
+	var peaks []peak
+	for y := r + 3; y < img.Height-r-3; y++ {
+		for x := r + 3; x < img.Width-r-3; x++ {
+			v := img.at(x, y)
+			if v <= threshold {
+				continue
+			}
+			// Strict maximum against the previous neighbours so plateaus yield a single peak
+			if v <= img.at(x-1, y) || v <= img.at(x-1, y-1) || v <= img.at(x, y-1) || v <= img.at(x+1, y-1) ||
+				v < img.at(x+1, y) || v < img.at(x-1, y+1) || v < img.at(x, y+1) || v < img.at(x+1, y+1) {
+				continue
+			}
+			// A real star has neighbours above the threshold too; single hot pixels don't
+			above := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if img.at(x+dx, y+dy) > threshold {
+						above++
+					}
+				}
+			}
+			if above < 3 {
+				continue
+			}
+			peaks = append(peaks, peak{x, y, v})
+		}
+	}
+
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].v > peaks[j].v })
+
+	var stars []starMeasure
+	var accepted []peak
+	for _, p := range peaks {
+		if len(accepted) >= maxStarsMeasured {
+			break
+		}
+		crowded := false
+		for _, a := range accepted {
+			if (a.x-p.x)*(a.x-p.x)+(a.y-p.y)*(a.y-p.y) < 4*r*r {
+				crowded = true
+				break
+			}
+		}
+		if crowded {
+			continue
+		}
+		accepted = append(accepted, p)
+		if s, ok := measureStar(img, p.x, p.y); ok {
+			stars = append(stars, s)
+		}
+	}
+
+	q.Stars = len(stars)
+	if q.Stars == 0 {
+		return q
+	}
+	var hfrs, fwhms, eccs []float64
+	for _, s := range stars {
+		hfrs = append(hfrs, s.hfr)
+		fwhms = append(fwhms, s.fwhm)
+		eccs = append(eccs, s.ecc)
+	}
+	scale := float64(img.Bin)
+	q.HFR = median(hfrs) * scale
+	q.FWHM = median(fwhms) * scale
+	q.Eccentricity = median(eccs)
+	return q
+}
+
+// measureStar computes HFR, FWHM and eccentricity of the star peaking at cx, cy
+// using the flux above the local background (median of a ring around the aperture)
+func measureStar(img *fitsImage, cx, cy int) (starMeasure, bool) {
+	r := starWindowRadius
+	var ring []float64
+	for dy := -r - 2; dy <= r+2; dy++ {
+		for dx := -r - 2; dx <= r+2; dx++ {
+			d2 := dx*dx + dy*dy
+			if d2 > r*r && d2 <= (r+2)*(r+2) {
+				ring = append(ring, float64(img.at(cx+dx, cy+dy)))
+			}
+		}
+	}
+	localBg := median(ring)
+
+	// Flux-weighted centroid
+	var sum, sx, sy float64
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if dx*dx+dy*dy > r*r {
+				continue
+			}
+			f := float64(img.at(cx+dx, cy+dy)) - localBg
+			if f <= 0 {
+				continue
+			}
+			sum += f
+			sx += f * float64(dx)
+			sy += f * float64(dy)
+		}
+	}
+	if sum <= 0 {
+		return starMeasure{}, false
+	}
+	mx, my := sx/sum, sy/sum
+
+	// Second moments and half-flux radius around the centroid
+	var sxx, syy, sxy, sdist float64
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if dx*dx+dy*dy > r*r {
+				continue
+			}
+			f := float64(img.at(cx+dx, cy+dy)) - localBg
+			if f <= 0 {
+				continue
+			}
+			ddx, ddy := float64(dx)-mx, float64(dy)-my
+			sxx += f * ddx * ddx
+			syy += f * ddy * ddy
+			sxy += f * ddx * ddy
+			sdist += f * math.Sqrt(ddx*ddx+ddy*ddy)
+		}
+	}
+	sxx, syy, sxy = sxx/sum, syy/sum, sxy/sum
+
+	// Eigenvalues of the covariance matrix give the major/minor axes
+	trace := sxx + syy
+	det := sxx*syy - sxy*sxy
+	disc := math.Sqrt(math.Max(trace*trace/4-det, 0))
+	a2, b2 := trace/2+disc, trace/2-disc
+	if a2 <= 0 || b2 < 0 {
+		return starMeasure{}, false
+	}
+
+	return starMeasure{
+		hfr:  sdist / sum,
+		fwhm: 2.3548 * math.Sqrt((a2+b2)/2),
+		ecc:  math.Sqrt(1 - b2/a2),
+	}, true
+}
+
+// backgroundStats returns the median and robust sigma (1.4826 × MAD) of a sample of the pixels
+func backgroundStats(data []float32) (float64, float64) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	step := max(1, len(data)/backgroundSample)
+	sample := make([]float64, 0, len(data)/step+1)
+	for i := 0; i < len(data); i += step {
+		sample = append(sample, float64(data[i]))
+	}
+	med := median(sample)
+	for i := range sample {
+		sample[i] = math.Abs(sample[i] - med)
+	}
+	return med, 1.4826 * median(sample)
+}
+
+// median returns the median of values (the slice is reordered)
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Camera raw formats whose EXIF metadata is read: TIFF-based raws (and TIFF
+// itself), Canon CR3 (ISO base media file) and Fujifilm RAF (embedded JPEG)
+var exifExtensions = []string{".cr2", ".cr3", ".nef", ".arw", ".dng", ".orf", ".raf", ".rw2", ".pef", ".tif", ".tiff"}
+
+// EXIF tags used by the tool
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagExposureTime     = 0x829a
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagOffsetOriginal   = 0x9011
+	tagFocalLength      = 0x920a
+	tagLensModel        = 0xa434
+)
+
+// Size in bytes of each TIFF field type
+var tiffTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8}
+
+// UUID of the box holding the EXIF data of a CR3 file
+var canonCR3UUID = []byte{0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0, 0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48}
+
+// exifData is the capture metadata read from a raw file
+type exifData struct {
+	Make        string
+	Model       string
+	Lens        string
+	Taken       string // DateTimeOriginal, "2006:01:02 15:04:05" in camera time
+	Offset      string // OffsetTimeOriginal, "+02:00", when the camera records it
+	Exposure    float64
+	ISO         uint32
+	FocalLength float64
+}
+
+// isEXIFFile reports whether the file name is a format whose EXIF metadata is read
+func isEXIFFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exifExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// readRawHeader reads the EXIF metadata of a camera raw file as the FITS
+// keywords the tool works with
+func readRawHeader(path string) (fitsHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var exif exifData
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cr3":
+		err = readCR3Exif(f, info.Size(), &exif)
+	case ".raf":
+		err = readRAFExif(f, &exif)
+	default:
+		err = readTIFFExif(io.NewSectionReader(f, 0, info.Size()), &exif)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return exif.header(), nil
+}
+
+// header converts the EXIF data: the capture time goes to DATE-OBS when the
+// camera recorded its UTC offset and to DATE-LOC otherwise, the lens to TELESCOP
+func (e exifData) header() fitsHeader {
+	h := fitsHeader{}
+	camera := e.Model
+	if brand := strings.Fields(e.Make); len(brand) > 0 && !strings.Contains(strings.ToLower(e.Model), strings.ToLower(brand[0])) {
+		camera = brand[0] + " " + e.Model
+	}
+	if camera = strings.TrimSpace(camera); camera != "" {
+		h["INSTRUME"] = camera
+	}
+	if e.Lens != "" {
+		h["TELESCOP"] = e.Lens
+	}
+	if e.FocalLength > 0 {
+		h["FOCALLEN"] = fmt.Sprintf("%g", e.FocalLength)
+	}
+	if e.Exposure > 0 {
+		h["EXPTIME"] = fmt.Sprintf("%g", e.Exposure)
+	}
+	if e.ISO > 0 {
+		h["ISOSPEED"] = fmt.Sprintf("%d", e.ISO)
+	}
+	if taken, err := time.Parse("2006:01:02 15:04:05", e.Taken); err == nil {
+		if offset, err := time.Parse("-07:00", e.Offset); err == nil {
+			_, seconds := offset.Zone()
+			utc := taken.Add(-time.Duration(seconds) * time.Second)
+			h["DATE-OBS"] = utc.Format("2006-01-02T15:04:05")
+		} else {
+			h["DATE-LOC"] = taken.Format("2006-01-02T15:04:05")
+		}
+	}
+	return h
+}
+
+// tiffFile reads the IFDs of a TIFF structure; offsets are relative to its start
+type tiffFile struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+// tiffEntry is a field of an IFD, with the value itself when it fits in 4 bytes
+type tiffEntry struct {
+	Type  uint16
+	Count uint32
+	Value [4]byte
+}
+
+type tiffIFD map[uint16]tiffEntry
+
+// openTIFF checks the byte order mark and returns the offset of the first IFD.
+// The magic number isn't checked: ORF and RW2 use their own.
+func openTIFF(r io.ReaderAt) (*tiffFile, uint32, error) {
+	var hdr [8]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil {
+		return nil, 0, errors.New("not a TIFF-based raw file")
+	}
+	t := &tiffFile{r: r}
+	switch string(hdr[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, errors.New("not a TIFF-based raw file")
+	}
+	return t, t.order.Uint32(hdr[4:]), nil
+}
+
+// readIFD reads the fields of the IFD at offset
+func (t *tiffFile) readIFD(offset uint32) (tiffIFD, error) {
+	var n [2]byte
+	if _, err := t.r.ReadAt(n[:], int64(offset)); err != nil {
+		return nil, fmt.Errorf("IFD at %d: %v", offset, err)
+	}
+	count := int(t.order.Uint16(n[:]))
+	if count == 0 || count > 1000 {
+		return nil, fmt.Errorf("corrupt IFD at %d", offset)
+	}
+	buf := make([]byte, 12*count)
+	if _, err := t.r.ReadAt(buf, int64(offset)+2); err != nil {
+		return nil, fmt.Errorf("IFD at %d: %v", offset, err)
+	}
+	ifd := tiffIFD{}
+	for i := 0; i < count; i++ {
+		b := buf[12*i:]
+		e := tiffEntry{Type: t.order.Uint16(b[2:]), Count: t.order.Uint32(b[4:])}
+		copy(e.Value[:], b[8:12])
+		ifd[t.order.Uint16(b)] = e
+	}
+	return ifd, nil
+}
+
+// data returns the bytes of a field, read from its offset when they don't fit in the entry
+func (t *tiffFile) data(e tiffEntry) []byte {
+	size := tiffTypeSizes[e.Type] * int(e.Count)
+	if size <= 4 {
+		return e.Value[:size]
+	}
+	if size > 1<<16 {
+		return nil
+	}
+	buf := make([]byte, size)
+	if _, err := t.r.ReadAt(buf, int64(t.order.Uint32(e.Value[:]))); err != nil {
+		return nil
+	}
+	return buf
+}
+
+// str returns an ASCII field
+func (t *tiffFile) str(ifd tiffIFD, tag uint16) string {
+	e, ok := ifd[tag]
+	if !ok || e.Type != 2 {
+		return ""
+	}
+	b := t.data(e)
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// uint returns a SHORT or LONG field
+func (t *tiffFile) uint(ifd tiffIFD, tag uint16) (uint32, bool) {
+	e, ok := ifd[tag]
+	if !ok || e.Count == 0 {
+		return 0, false
+	}
+	switch e.Type {
+	case 3:
+		return uint32(t.order.Uint16(e.Value[:])), true
+	case 4:
+		return t.order.Uint32(e.Value[:]), true
+	}
+	return 0, false
+}
+
+// rational returns a RATIONAL or SRATIONAL field
+func (t *tiffFile) rational(ifd tiffIFD, tag uint16) (float64, bool) {
+	e, ok := ifd[tag]
+	if !ok || (e.Type != 5 && e.Type != 10) || e.Count == 0 {
+		return 0, false
+	}
+	b := t.data(e)
+	if len(b) < 8 {
+		return 0, false
+	}
+	num, den := t.order.Uint32(b), t.order.Uint32(b[4:])
+	if den == 0 {
+		return 0, false
+	}
+	if e.Type == 10 {
+		return float64(int32(num)) / float64(int32(den)), true
+	}
+	return float64(num) / float64(den), true
+}
+
+// readIFD0 fills the camera fields of the main IFD and returns the Exif IFD offset
+func (t *tiffFile) readIFD0(ifd tiffIFD, exif *exifData) (uint32, bool) {
+	exif.Make = t.str(ifd, tagMake)
+	exif.Model = t.str(ifd, tagModel)
+	if exif.Taken == "" {
+		exif.Taken = t.str(ifd, tagDateTime)
+	}
+	return t.uint(ifd, tagExifIFD)
+}
+
+// readExifIFD fills the capture fields of an Exif IFD
+func (t *tiffFile) readExifIFD(ifd tiffIFD, exif *exifData) {
+	if taken := t.str(ifd, tagDateTimeOriginal); taken != "" {
+		exif.Taken = taken
+	}
+	exif.Offset = t.str(ifd, tagOffsetOriginal)
+	exif.Exposure, _ = t.rational(ifd, tagExposureTime)
+	exif.ISO, _ = t.uint(ifd, tagISO)
+	exif.FocalLength, _ = t.rational(ifd, tagFocalLength)
+	exif.Lens = t.str(ifd, tagLensModel)
+}
+
+// readTIFFExif reads IFD0 and the Exif IFD of a TIFF-based file (CR2, NEF, ARW, DNG...)
+func readTIFFExif(r io.ReaderAt, exif *exifData) error {
+	t, offset, err := openTIFF(r)
+	if err != nil {
+		return err
+	}
+	ifd0, err := t.readIFD(offset)
+	if err != nil {
+		return err
+	}
+	exifOffset, ok := t.readIFD0(ifd0, exif)
+	if !ok {
+		return nil
+	}
+	ifd, err := t.readIFD(exifOffset)
+	if err != nil {
+		return err
+	}
+	t.readExifIFD(ifd, exif)
+	return nil
+}
+
+// bmffBoxes calls fn with the type, body start and end of every box between
+// start and end of an ISO base media file, until fn returns false
+func bmffBoxes(r io.ReaderAt, start, end int64, fn func(kind string, body, bodyEnd int64) bool) error {
+	for pos := start; pos+8 <= end; {
+		var hdr [16]byte
+		if _, err := r.ReadAt(hdr[:8], pos); err != nil {
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		kind := string(hdr[4:8])
+		body := pos + 8
+		switch size {
+		case 0:
+			size = end - pos
+		case 1:
+			if _, err := r.ReadAt(hdr[8:16], pos+8); err != nil {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			body += 8
+		}
+		if size < body-pos || pos+size > end {
+			return fmt.Errorf("corrupt %s box", kind)
+		}
+		if !fn(kind, body, pos+size) {
+			return nil
+		}
+		pos += size
+	}
+	return nil
+}
+
+// readCR3Exif reads the CMT1 (IFD0) and CMT2 (Exif IFD) TIFF boxes of a CR3 file
+func readCR3Exif(r io.ReaderAt, size int64, exif *exifData) error {
+	found := false
+	var boxErr error
+	err := bmffBoxes(r, 0, size, func(kind string, body, end int64) bool {
+		if kind != "moov" {
+			return true
+		}
+		boxErr = bmffBoxes(r, body, end, func(kind string, body, end int64) bool {
+			uuid := make([]byte, 16)
+			if kind != "uuid" || end-body < 16 {
+				return true
+			}
+			if _, err := r.ReadAt(uuid, body); err != nil || !bytes.Equal(uuid, canonCR3UUID) {
+				return true
+			}
+			found = true
+			return bmffBoxes(r, body+16, end, func(kind string, body, end int64) bool {
+				switch kind {
+				case "CMT1", "CMT2":
+					t, offset, err := openTIFF(io.NewSectionReader(r, body, end-body))
+					if err != nil {
+						return true
+					}
+					ifd, err := t.readIFD(offset)
+					if err != nil {
+						return true
+					}
+					if kind == "CMT1" {
+						t.readIFD0(ifd, exif)
+					} else {
+						t.readExifIFD(ifd, exif)
+					}
+				}
+				return true
+			}) == nil
+		})
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	if boxErr != nil {
+		return boxErr
+	}
+	if !found {
+		return errors.New("no Canon metadata box in the CR3 file")
+	}
+	return nil
+}
+
+// readRAFExif reads the EXIF data of the JPEG preview embedded in a Fujifilm RAF file
+func readRAFExif(r io.ReaderAt, exif *exifData) error {
+	var hdr [92]byte
+	if _, err := r.ReadAt(hdr[:], 0); err != nil || string(hdr[:16]) != "FUJIFILMCCD-RAW " {
+		return errors.New("not a Fujifilm RAF file")
+	}
+	offset := int64(binary.BigEndian.Uint32(hdr[84:88]))
+	length := int64(binary.BigEndian.Uint32(hdr[88:92]))
+	return readJPEGExif(io.NewSectionReader(r, offset, length), exif)
+}
+
+// readJPEGExif finds the APP1 Exif segment of a JPEG and reads its TIFF structure
+func readJPEGExif(r *io.SectionReader, exif *exifData) error {
+	var marker [4]byte
+	if _, err := r.ReadAt(marker[:2], 0); err != nil || marker[0] != 0xff || marker[1] != 0xd8 {
+		return errors.New("no JPEG preview")
+	}
+	for pos := int64(2); pos+4 <= r.Size(); {
+		if _, err := r.ReadAt(marker[:], pos); err != nil || marker[0] != 0xff {
+			break
+		}
+		length := int64(binary.BigEndian.Uint16(marker[2:]))
+		if marker[1] == 0xda { // image data follows, no metadata after it
+			break
+		}
+		if marker[1] == 0xe1 && length > 8 {
+			id := make([]byte, 6)
+			if _, err := r.ReadAt(id, pos+4); err == nil && string(id) == "Exif\x00\x00" {
+				return readTIFFExif(io.NewSectionReader(r, pos+10, length-8), exif)
+			}
+		}
+		pos += 2 + length
+	}
+	return errors.New("no EXIF data in the JPEG preview")
+}
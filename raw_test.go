@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testTag is an IFD field written by buildTIFF: string (ASCII), uint16 (SHORT),
+// uint32 (LONG) or [2]uint32 (RATIONAL)
+type testTag struct {
+	tag   uint16
+	value any
+}
+
+// buildIFD encodes an IFD that starts at offset start, its long values following it
+func buildIFD(order binary.AppendByteOrder, start int, tags []testTag) []byte {
+	sort.Slice(tags, func(i, j int) bool { return tags[i].tag < tags[j].tag })
+	dataStart := start + 2 + 12*len(tags) + 4
+	var entries, data []byte
+	for _, t := range tags {
+		var typ uint16
+		var raw []byte
+		count := 1
+		switch v := t.value.(type) {
+		case string:
+			typ, raw, count = 2, append([]byte(v), 0), len(v)+1
+		case uint16:
+			typ, raw = 3, order.AppendUint16(nil, v)
+		case uint32:
+			typ, raw = 4, order.AppendUint32(nil, v)
+		case [2]uint32:
+			typ, raw = 5, order.AppendUint32(order.AppendUint32(nil, v[0]), v[1])
+		}
+		entries = order.AppendUint16(entries, t.tag)
+		entries = order.AppendUint16(entries, typ)
+		entries = order.AppendUint32(entries, uint32(count))
+		if len(raw) <= 4 {
+			entries = append(entries, append(raw, make([]byte, 4-len(raw))...)...)
+			continue
+		}
+		entries = order.AppendUint32(entries, uint32(dataStart+len(data)))
+		data = append(data, raw...)
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
+	}
+	ifd := order.AppendUint16(nil, uint16(len(tags)))
+	ifd = append(ifd, entries...)
+	ifd = order.AppendUint32(ifd, 0)
+	return append(ifd, data...)
+}
+
+// buildTIFF encodes a TIFF structure with IFD0 and, when exif is not nil, an Exif IFD
+func buildTIFF(bigEndian bool, ifd0, exif []testTag) []byte {
+	var order binary.AppendByteOrder = binary.LittleEndian
+	out := []byte("II*\x00")
+	if bigEndian {
+		order = binary.BigEndian
+		out = []byte("MM\x00*")
+	}
+	out = order.AppendUint32(out, 8)
+	if exif == nil {
+		return append(out, buildIFD(order, 8, ifd0)...)
+	}
+	withPointer := append(append([]testTag{}, ifd0...), testTag{tagExifIFD, uint32(0)})
+	exifStart := 8 + len(buildIFD(order, 8, withPointer))
+	withPointer[len(withPointer)-1].value = uint32(exifStart)
+	out = append(out, buildIFD(order, 8, withPointer)...)
+	return append(out, buildIFD(order, exifStart, exif)...)
+}
+
+// bmffBox encodes an ISO base media box
+func bmffBox(kind string, body ...[]byte) []byte {
+	joined := bytes.Join(body, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(joined)))
+	return append(append(box, kind...), joined...)
+}
+
+func TestReadRawHeader(t *testing.T) {
+	canonIFD0 := []testTag{{tagMake, "Canon"}, {tagModel, "Canon EOS 6D"}, {tagDateTime, "2024:03:01 23:00:00"}}
+	canonExif := []testTag{
+		{tagExposureTime, [2]uint32{300, 1}},
+		{tagISO, uint16(1600)},
+		{tagDateTimeOriginal, "2024:03:01 22:15:30"},
+		{tagLensModel, "EF100-400mm f/4.5-5.6L IS II USM"},
+		{tagFocalLength, [2]uint32{400, 1}},
+	}
+	canonHeader := fitsHeader{"INSTRUME": "Canon EOS 6D", "TELESCOP": "EF100-400mm f/4.5-5.6L IS II USM", "FOCALLEN": "400",
+		"EXPTIME": "300", "ISOSPEED": "1600", "DATE-LOC": "2024-03-01T22:15:30"}
+
+	fujiTIFF := buildTIFF(false, []testTag{{tagMake, "FUJIFILM"}, {tagModel, "X-T3"}},
+		[]testTag{{tagExposureTime, [2]uint32{120, 1}}, {tagISO, uint16(640)}, {tagDateTimeOriginal, "2024:03:05 21:00:00"}})
+	app1 := append([]byte("Exif\x00\x00"), fujiTIFF...)
+	jpeg := append([]byte{0xff, 0xd8, 0xff, 0xe1}, binary.BigEndian.AppendUint16(nil, uint16(len(app1)+2))...)
+	jpeg = append(append(jpeg, app1...), 0xff, 0xda, 0, 2, 0xff, 0xd9)
+	raf := append([]byte("FUJIFILMCCD-RAW 0201FF383501"), make([]byte, 100-28)...)
+	binary.BigEndian.PutUint32(raf[84:], 100)
+	binary.BigEndian.PutUint32(raf[88:], uint32(len(jpeg)))
+	raf = append(raf, jpeg...)
+
+	cr3 := append(bmffBox("ftyp", []byte("crx \x00\x00\x00\x01crx isom")),
+		bmffBox("moov", bmffBox("mvhd", make([]byte, 20)),
+			bmffBox("uuid", canonCR3UUID, bmffBox("CCTP", make([]byte, 8)),
+				bmffBox("CMT1", buildTIFF(false, canonIFD0, nil)),
+				bmffBox("CMT2", buildTIFF(false, canonExif, nil))))...)
+	cr3 = append(cr3, bmffBox("mdat", make([]byte, 64))...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    fitsHeader
+		wantErr string
+	}{
+		{name: "IMG_0001.CR2", data: buildTIFF(false, canonIFD0, canonExif), want: canonHeader},
+		{
+			name: "DSC_0001.nef",
+			data: buildTIFF(true, []testTag{{tagMake, "NIKON CORPORATION"}, {tagModel, "NIKON D810"}},
+				[]testTag{{tagExposureTime, [2]uint32{1, 4}}, {tagISO, uint16(800)}, {tagDateTimeOriginal, "2024:03:02 01:10:00"}, {tagOffsetOriginal, "+01:00"}}),
+			want: fitsHeader{"INSTRUME": "NIKON D810", "EXPTIME": "0.25", "ISOSPEED": "800", "DATE-OBS": "2024-03-02T00:10:00"},
+		},
+		{
+			name: "scan.tif",
+			data: buildTIFF(false, []testTag{{tagModel, "Scanner"}, {tagDateTime, "2024:03:01 23:00:00"}}, nil),
+			want: fitsHeader{"INSTRUME": "Scanner", "DATE-LOC": "2024-03-01T23:00:00"},
+		},
+		{name: "IMG_0002.CR3", data: cr3, want: canonHeader},
+		{name: "DSCF0001.RAF", data: raf, want: fitsHeader{"INSTRUME": "FUJIFILM X-T3", "EXPTIME": "120", "ISOSPEED": "640", "DATE-LOC": "2024-03-05T21:00:00"}},
+
+		{name: "text.cr2", data: []byte("not a raw file at all"), wantErr: "not a TIFF-based raw file"},
+		{name: "empty-ifd.dng", data: append([]byte("II*\x00\x08\x00\x00\x00"), 0, 0, 0, 0, 0, 0), wantErr: "corrupt IFD"},
+		{name: "other.cr3", data: append(bmffBox("ftyp", []byte("isom")), bmffBox("moov", bmffBox("mvhd", make([]byte, 20)))...), wantErr: "no Canon metadata box"},
+		{name: "broken.cr3", data: append(bmffBox("ftyp", []byte("crx ")), 0, 0, 1, 0, 'm', 'o', 'o', 'v'), wantErr: "corrupt moov box"},
+		{name: "other.raf", data: make([]byte, 120), wantErr: "not a Fujifilm RAF file"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readRawHeader(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Audit log kept in every Rejected/.../Night_ folder
+const rejectionLogFile = "rejections.csv"
+
+// rejection is a frame to move into the Rejected mirror and why
+type rejection struct {
+	Path   string
+	Reason string
+	Source string // rule engine that decided it ("cull", "logs"...)
+}
+
+// rejectedMirrorPath maps a capture folder under baseDir to its Rejected mirror,
+// e.g. <base>/M81/2026/Feb/Night_12 -> <base>/Rejected/M81/2026/Feb/Night_12
+func rejectedMirrorPath(baseDir, sessionPath string) (string, error) {
+	absSession, err := filepath.Abs(sessionPath)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(baseDir, absSession)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("'%s' is not inside %s", sessionPath, baseDir)
+	}
+	if first := strings.Split(filepath.ToSlash(rel), "/")[0]; first == rejectedFolder {
+		return "", fmt.Errorf("'%s' is already inside the %s mirror", sessionPath, rejectedFolder)
+	}
+	return filepath.Join(baseDir, rejectedFolder, rel), nil
+}
+
+// rejectFrames moves the frames of a session into its Rejected mirror, keeping their
+// subfolder (Lights/, Flats/...), and appends the decisions to the rejection log
+func rejectFrames(baseDir, sessionPath string, frames []rejection) error {
+	if len(frames) == 0 {
+		return nil
+	}
+	mirror, err := rejectedMirrorPath(baseDir, sessionPath)
+	if err != nil {
+		return err
+	}
+
+	var jobs []moveJob
+	for _, f := range frames {
+		rel, err := filepath.Rel(sessionPath, filepath.Dir(f.Path))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("'%s' is not inside the session", f.Path)
+		}
+		jobs = append(jobs, moveJob{Src: f.Path, DestDir: filepath.Join(mirror, rel)})
+	}
+
+	moved := moveWithProgress(jobs)
+	movedTo := map[string]string{}
+	for _, m := range moved {
+		movedTo[m.Source] = m.Path
+	}
+
+	var rows [][]string
+	now := time.Now().Format(time.RFC3339)
+	for _, f := range frames {
+		dest, ok := movedTo[f.Path]
+		if !ok {
+			continue
+		}
+		relDest, _ := filepath.Rel(mirror, dest)
+		rows = append(rows, []string{now, filepath.ToSlash(relDest), f.Source, f.Reason})
+	}
+	trPrintf("🗑️  %d frames moved to %s\n", len(rows), mirror)
+	return appendRejectionLog(mirror, rows)
+}
+
+// appendRejectionLog appends rows (time, file, source, reason) to rejections.csv
+func appendRejectionLog(mirror string, rows [][]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	logPath := filepath.Join(mirror, rejectionLogFile)
+	_, statErr := os.Stat(logPath)
+
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if os.IsNotExist(statErr) {
+		w.Write([]string{"time", "file", "source", "reason"})
+	}
+	w.WriteAll(rows)
+	return w.Error()
+}
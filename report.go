@@ -0,0 +1,197 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand("report", "report <target or Night_ session folder>",
+		"Show the integration time of the lights per filter; mosaic targets get one block per Panel_ folder and the panel with the least data.",
+		runReportCommand)
+}
+
+// filterIntegration is the light frames of one filter
+type filterIntegration struct {
+	Frames  int
+	Seconds float64
+}
+
+// panelIntegration is what a mosaic panel (or a regular target) has collected
+type panelIntegration struct {
+	Panel   string          // Panel_ folder, empty outside mosaics
+	Nights  map[string]bool // distinct nights ("2024-10-05")
+	Filters map[string]*filterIntegration
+}
+
+// total returns the number of lights and the integration time of the panel
+func (p *panelIntegration) total() (frames int, seconds float64) {
+	for _, f := range p.Filters {
+		frames += f.Frames
+		seconds += f.Seconds
+	}
+	return frames, seconds
+}
+
+func runReportCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: " + commands["report"].usage)
+	}
+	root := cleanPath(args[0])
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("'%s' is not a folder", root)
+	}
+	// Loads the filename_patterns that describe frames without headers
+	if _, _, err := commandBaseDir(); err != nil {
+		return err
+	}
+
+	sessions := findSessionFolders(root, false)
+	if len(sessions) == 0 {
+		if _, err := os.Stat(filepath.Join(root, "Lights")); err == nil {
+			sessions = []string{root}
+		}
+	}
+	if len(sessions) == 0 {
+		return fmt.Errorf("no sessions found in %s", root)
+	}
+
+	panels := map[string]*panelIntegration{}
+	for _, session := range sessions {
+		panel := sessionPanel(root, session)
+		p, ok := panels[panel]
+		if !ok {
+			p = &panelIntegration{Panel: panel, Nights: map[string]bool{}, Filters: map[string]*filterIntegration{}}
+			panels[panel] = p
+		}
+		// Layouts with {equipment} or {site} can hold one night in several sessions
+		night, dated := sessionNight(session)
+		counted := dated
+		for _, path := range listFrameFiles(filepath.Join(session, "Lights")) {
+			header, err := readFrameHeader(path)
+			if err != nil {
+				continue
+			}
+			if !dated {
+				if n, err := frameNight(header, path, nil); err == nil {
+					p.Nights[n.Format("2006-01-02")] = true
+					counted = true
+				}
+			}
+			filter := strings.TrimSpace(header.String("FILTER"))
+			if filter == "" {
+				filter = "none"
+			}
+			f, ok := p.Filters[filter]
+			if !ok {
+				f = &filterIntegration{}
+				p.Filters[filter] = f
+			}
+			f.Frames++
+			if exp, ok := exposureTime(header); ok {
+				f.Seconds += exp
+			}
+		}
+		if dated {
+			p.Nights[night] = true
+		} else if !counted {
+			p.Nights[session] = true
+		}
+	}
+
+	names := make([]string, 0, len(panels))
+	for name := range panels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	trPrintf("📊 Integration in %s (%d sessions)\n", filepath.Base(root), len(sessions))
+	var weakest *panelIntegration
+	var weakestSeconds, totalSeconds float64
+	totalFrames := 0
+	for _, name := range names {
+		p := panels[name]
+		title := p.Panel
+		if title == "" {
+			title = tr("Target")
+			if len(panels) > 1 {
+				title = tr("Outside panels")
+			}
+		}
+		frames, seconds := p.total()
+		totalFrames += frames
+		totalSeconds += seconds
+		trPrintf("\n%s: %d nights, %d lights, %s\n", title, len(p.Nights), frames, formatIntegration(seconds))
+
+		filters := make([]string, 0, len(p.Filters))
+		for f := range p.Filters {
+			filters = append(filters, f)
+		}
+		sort.Strings(filters)
+		for _, f := range filters {
+			fi := p.Filters[f]
+			trPrintf("   %-10s %5d lights  %s\n", f, fi.Frames, formatIntegration(fi.Seconds))
+		}
+
+		if p.Panel != "" && (weakest == nil || seconds < weakestSeconds) {
+			weakest, weakestSeconds = p, seconds
+		}
+	}
+
+	if len(panels) > 1 {
+		trPrintf("\nTotal: %d lights, %s\n", totalFrames, formatIntegration(totalSeconds))
+	}
+	if weakest != nil && len(panels) > 1 {
+		trPrintf("⚠️  %s has the least integration (%s).\n", weakest.Panel, formatIntegration(weakestSeconds))
+	}
+	return nil
+}
+
+// sessionPanel returns the Panel_ folder a session belongs to, from the path
+// below root or, when root is inside the panel, from session.json
+func sessionPanel(root, session string) string {
+	if rel, err := filepath.Rel(root, session); err == nil {
+		for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+			if strings.HasPrefix(part, panelFolderPrefix) {
+				return part
+			}
+		}
+	}
+	if meta, err := loadSessionMetadata(session); err == nil && meta != nil && meta.Panel != "" {
+		return panelFolder(meta.Panel)
+	}
+	return ""
+}
+
+// sessionNight returns the night of a session ("2024-10-05") from its session.json
+func sessionNight(session string) (string, bool) {
+	meta, err := loadSessionMetadata(session)
+	if err != nil || meta == nil {
+		return "", false
+	}
+	month, ok := parseMonthFolder(meta.Date.Month)
+	if !ok {
+		return "", false
+	}
+	year, errYear := strconv.Atoi(meta.Date.Year)
+	day, errDay := strconv.Atoi(meta.Date.Day)
+	if errYear != nil || errDay != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", year, month, day), true
+}
+
+// formatIntegration prints an integration time as "5h 12m"
+func formatIntegration(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second)).Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
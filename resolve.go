@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Minimum similar-folder score for an automatic resolution to reuse an existing
+// folder (same name, target.json alias or shared catalog designation)
+const autoReuseScore = 0.9
+
+// resolvedTarget is the folder a target is filed into and the objects it holds
+type resolvedTarget struct {
+	Folder  string
+	Objects []targetObject
+	Reused  bool // Folder already existed in baseDir
+}
+
+// resolveTargetAuto resolves an object name (e.g. a FITS OBJECT value) through
+// Sesame, keeping the primary designation, and reuses an existing folder that
+// clearly holds the same target instead of creating a new one. Names Sesame
+// doesn't know ("Target 3") fall back to the nearest object to pos, when given.
+func resolveTargetAuto(baseDir, name string, pos *skyPosition, naming fieldNamingConfig) resolvedTarget {
+	res := resolveObject(name)
+	if !res.Found && pos != nil {
+		if matches := lookupNearbyObjects(pos.RA, pos.Dec); len(matches) > 0 {
+			trPrintf("-> '%s' is not a known object, using the nearest one: %s\n", name, matches[0].describe())
+			name = matches[0].Designation
+			if res = resolveObject(name); !res.Found {
+				res = matches[0].sesameResult()
+			}
+		}
+	}
+	formatted := formatTargetName(name)
+
+	techName := formatted
+	if len(res.TechnicalOptions) > 0 {
+		techName = res.TechnicalOptions[0]
+	}
+	objects := []targetObject{newTargetObject(techName, res)}
+	folder := naming.fieldFolderName(objects)
+	target := resolvedTarget{Folder: folder, Objects: objects}
+
+	keys := []string{name, formatted, techName, res.CommonName, folder}
+	keys = append(keys, res.TechnicalOptions...)
+	keys = append(keys, res.Aliases...)
+	if candidates := findSimilarFolders(baseDir, keys); len(candidates) > 0 && candidates[0].Score >= autoReuseScore {
+		target.Folder = candidates[0].Name
+		target.Reused = true
+	}
+	return target
+}
+
+// resolveTargetInteractive resolves the objects of a target (e.g. the words typed
+// by the user, or a FITS OBJECT value) through Sesame, asks which designation to
+// use when there are several and offers the existing folders that may already
+// hold the same target. The folder is named by naming.fieldFolderName.
+func resolveTargetInteractive(reader *bufio.Reader, baseDir, input string, targets []string, naming fieldNamingConfig) resolvedTarget {
+	matchKeys := []string{input}
+	var targetObjects []targetObject
+
+	trPrintf("\nSearching for information on '%s' in SIMBAD/Sesame...\n", input)
+
+	for _, t := range targets {
+		formatted := formatTargetName(t)
+		res := resolveObject(t)
+		cName, tOptions := res.CommonName, res.TechnicalOptions
+
+		techName := formatted
+		if len(tOptions) > 0 {
+			if len(tOptions) == 1 {
+				techName = tOptions[0]
+				trPrintf("-> [%s] Using primary technical designation: %s\n", t, techName)
+			} else {
+				trPrintf("\nMultiple catalog designations found for [%s]:\n", t)
+				for i, opt := range tOptions {
+					fmt.Printf("  %d) %s\n", i+1, opt)
+				}
+				trPrintf("  %d) Keep original: %s\n", len(tOptions)+1, formatted)
+				trPrintf("Which nomenclature do you prefer for the main folder? (1-%d) [1]: ", len(tOptions)+1)
+
+				optInput := readInput(reader)
+				if optInput == "" {
+					optInput = "1"
+				}
+				idx, err := strconv.Atoi(optInput)
+				if err == nil && idx >= 1 && idx <= len(tOptions) {
+					techName = tOptions[idx-1]
+				}
+			}
+		} else if cName == "" {
+			trPrintf("-> Object [%s] not found or without common name (only using '%s').\n", t, formatted)
+		}
+
+		matchKeys = append(matchKeys, formatted, techName, cName)
+		matchKeys = append(matchKeys, tOptions...)
+		matchKeys = append(matchKeys, res.Aliases...)
+		targetObjects = append(targetObjects, newTargetObject(techName, res))
+	}
+
+	naming.sortFieldObjects(targetObjects)
+	finalTargetFolder := naming.fieldFolderName(targetObjects)
+
+	reused := false
+	matchKeys = append(matchKeys, finalTargetFolder)
+	candidates := findSimilarFolders(baseDir, matchKeys)
+
+	if len(candidates) > 0 && candidates[0].Name != finalTargetFolder {
+		fmt.Println()
+		fmt.Println(tr("⚠️  Existing folders that may hold the same target were found:"))
+		for i, c := range candidates {
+			fmt.Printf("  %d) '%s'  [%s]\n", i+1, c.Name, c.Reason)
+		}
+		trPrintf("    The new standardized format is: '%s'\n", finalTargetFolder)
+		fmt.Println(tr("\nWhat do you want to do?"))
+		trPrintf("  1-%d) Use that existing folder as is and add the new session inside.\n", len(candidates))
+		trPrintf("  r1-r%d) Rename that existing folder to '%s' and add the new session there.\n", len(candidates), finalTargetFolder)
+		trPrintf("  n) Ignore and create '%s' as a completely new folder.\n", finalTargetFolder)
+
+		for {
+			trPrintf("Choose an option (1-%d/r1-r%d/n) [1]: ", len(candidates), len(candidates))
+			resp := strings.ToLower(readInput(reader))
+			if resp == "" {
+				resp = "1"
+			}
+
+			if resp == "n" {
+				trPrintf("-> We will create a new folder: '%s'\n", finalTargetFolder)
+				break
+			}
+
+			rename := strings.HasPrefix(resp, "r")
+			idx, err := strconv.Atoi(strings.TrimPrefix(resp, "r"))
+			if err != nil || idx < 1 || idx > len(candidates) {
+				fmt.Println(tr("Invalid option."))
+				continue
+			}
+			similarFolder := candidates[idx-1].Name
+
+			reused = true
+			if !rename {
+				finalTargetFolder = similarFolder
+				trPrintf("-> We will operate inside: '%s'\n", finalTargetFolder)
+				break
+			}
+
+			if err := renameTargetFolder(baseDir, similarFolder, finalTargetFolder); err != nil {
+				trPrintf("-> Error renaming the folder: %v\n", err)
+				fmt.Println(tr("-> We will operate with the original name for safety."))
+				finalTargetFolder = similarFolder
+			} else {
+				trPrintf("-> Folder successfully renamed to '%s'!\n", finalTargetFolder)
+			}
+			break
+		}
+	}
+
+	return resolvedTarget{Folder: finalTargetFolder, Objects: targetObjects, Reused: reused}
+}
+
+// renameTargetFolder renames a target folder together with its Rejected mirror,
+// so both keep the same name. It refuses when the mirror's new name is taken.
+func renameTargetFolder(baseDir, oldName, newName string) error {
+	oldMirror := filepath.Join(baseDir, rejectedFolder, oldName)
+	newMirror := filepath.Join(baseDir, rejectedFolder, newName)
+	_, err := os.Stat(oldMirror)
+	hasMirror := err == nil
+	if hasMirror {
+		if _, err := os.Stat(newMirror); err == nil {
+			return fmt.Errorf("%s already exists", newMirror)
+		}
+	}
+
+	if err := os.Rename(filepath.Join(baseDir, oldName), filepath.Join(baseDir, newName)); err != nil {
+		return err
+	}
+	if hasMirror {
+		if err := os.Rename(oldMirror, newMirror); err != nil {
+			os.Rename(filepath.Join(baseDir, newName), filepath.Join(baseDir, oldName))
+			return err
+		}
+	}
+	return nil
+}
+
+// chooseNearbyObject proposes the catalogued objects near a position and returns
+// the names to resolve: the chosen designation or what the user typed instead
+func chooseNearbyObject(reader *bufio.Reader, ra, dec float64) []string {
+	trPrintf("\nLooking for catalogued objects near %s...\n", formatCoordinates(ra, dec))
+	matches := lookupNearbyObjects(ra, dec)
+	if len(matches) == 0 {
+		trPrintf("-> No catalogued object within %g°.\n", coneSearchRadius)
+		fmt.Print(tr("Target name (empty skips): "))
+		return strings.Fields(readInput(reader))
+	}
+
+	for i, m := range matches {
+		fmt.Printf("  %d) %s\n", i+1, m.describe())
+	}
+	for {
+		trPrintf("Which object names the folder? (1-%d, or type a name) [1]: ", len(matches))
+		resp := readInput(reader)
+		if resp == "" {
+			resp = "1"
+		}
+		if idx, err := strconv.Atoi(resp); err == nil {
+			if idx >= 1 && idx <= len(matches) {
+				return []string{matches[idx-1].Designation}
+			}
+			fmt.Println(tr("Invalid option."))
+			continue
+		}
+		return strings.Fields(resp)
+	}
+}
+
+// isPlaceholderObject reports OBJECT values written by capture programs for
+// frames that don't belong to a target (flat wizards, darks...)
+func isPlaceholderObject(object string) bool {
+	switch strings.ToLower(strings.TrimSpace(object)) {
+	case "", "flat", "flats", "flatwizard", "flat wizard", "dark", "darks", "bias", "snapshot", "capture", "unknown":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand("restore", "restore [-all] [-list file] <Night_ session folder> [pattern...]",
+		"Move frames back from the Rejected mirror into the session (by glob pattern, a list file, or all).",
+		runRestoreCommand)
+}
+
+func runRestoreCommand(args []string) error {
+	baseDir, _, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	all := fs.Bool("all", false, "restore every rejected frame of the session")
+	listFile := fs.String("list", "", "file with one frame name or relative path per line")
+	fs.Parse(args)
+	if fs.NArg() < 1 || (!*all && *listFile == "" && fs.NArg() < 2) {
+		return errors.New("usage: " + commands["restore"].usage)
+	}
+
+	sessionPath := cleanPath(fs.Arg(0))
+	mirror, err := rejectedMirrorPath(baseDir, sessionPath)
+	if err != nil {
+		return err
+	}
+
+	patterns := fs.Args()[1:]
+	if *listFile != "" {
+		names, err := readListFile(cleanPath(*listFile))
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, names...)
+	}
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %v", p, err)
+		}
+	}
+
+	var jobs []moveJob
+	filepath.WalkDir(mirror, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") || d.Name() == rejectionLogFile {
+			return nil
+		}
+		rel, _ := filepath.Rel(mirror, path)
+		if *all || matchesAnyPattern(patterns, rel) {
+			jobs = append(jobs, moveJob{Src: path, DestDir: filepath.Join(sessionPath, filepath.Dir(rel))})
+		}
+		return nil
+	})
+	if len(jobs) == 0 {
+		return fmt.Errorf("no rejected frames matched in '%s'", mirror)
+	}
+
+	trPrintf("Restoring %d frames into %s...\n", len(jobs), sessionPath)
+	moved := moveWithProgress(jobs)
+
+	var rows [][]string
+	now := time.Now().Format(time.RFC3339)
+	for _, m := range moved {
+		rel, _ := filepath.Rel(mirror, m.Source)
+		rows = append(rows, []string{now, filepath.ToSlash(rel), "restore", "restored to " + filepath.ToSlash(filepath.Dir(rel))})
+	}
+	if err := appendRejectionLog(mirror, rows); err != nil {
+		trPrintf("⚠️  Could not update %s: %v\n", rejectionLogFile, err)
+	}
+	trPrintf("✅ %d frames restored.\n", len(moved))
+	return nil
+}
+
+// matchesAnyPattern matches a relative path (or its base name) against glob patterns
+func matchesAnyPattern(patterns []string, rel string) bool {
+	slashRel := filepath.ToSlash(rel)
+	base := filepath.Base(rel)
+	for _, p := range patterns {
+		p = filepath.ToSlash(p)
+		if ok, _ := filepath.Match(p, slashRel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readListFile returns the non-empty, non-comment lines of a file
+func readListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// Limits of the file systems the archive may live on (NTFS, SMB shares, exFAT
+// cards): 255 UTF-16 units per name and, for many Windows tools, 260 per path
+const (
+	maxNameUnits = 255
+	maxPathUnits = 260
+
+	// Room left in a session path for the frame file names below it
+	fileNameReserve = 60
+)
+
+// Device names Windows refuses as file or folder names, with or without extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ASCII spellings of the letters and signs found in object, site and equipment names
+var transliterations = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Ą': "A", 'Æ': "AE",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ą': "a", 'æ': "ae",
+	'Ç': "C", 'Ć': "C", 'Č': "C", 'ç': "c", 'ć': "c", 'č': "c",
+	'Ð': "D", 'Ď': "D", 'ð': "d", 'ď': "d",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ę': "E", 'Ě': "E",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ę': "e", 'ě': "e",
+	'Ğ': "G", 'ğ': "g",
+	'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I", 'İ': "I", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ı': "i",
+	'Ł': "L", 'ł': "l",
+	'Ñ': "N", 'Ń': "N", 'Ň': "N", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O", 'Ő': "O", 'Œ': "OE",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ő': "o", 'œ': "oe",
+	'Ř': "R", 'ř': "r",
+	'Ś': "S", 'Š': "S", 'Ş': "S", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss",
+	'Ť': "T", 'ť': "t", 'Þ': "Th", 'þ': "th",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ů': "U", 'Ű': "U",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ů': "u", 'ű': "u",
+	'Ý': "Y", 'ý': "y", 'ÿ': "y",
+	'Ź': "Z", 'Ż': "Z", 'Ž': "Z", 'ź': "z", 'ż': "z", 'ž': "z",
+	'α': "alpha", 'β': "beta", 'γ': "gamma", 'δ': "delta", 'ε': "epsilon", 'ζ': "zeta",
+	'η': "eta", 'θ': "theta", 'ι': "iota", 'κ': "kappa", 'λ': "lambda", 'μ': "mu",
+	'ν': "nu", 'ξ': "xi", 'ο': "omicron", 'π': "pi", 'ρ': "rho", 'σ': "sigma",
+	'τ': "tau", 'υ': "upsilon", 'φ': "phi", 'χ': "chi", 'ψ': "psi", 'ω': "omega",
+	'‘': "'", '’': "'", '“': "'", '”': "'", '–': "-", '—': "-", '…': "...", '°': "deg", '×': "x",
+}
+
+// transliterate spells a name with ASCII letters only, dropping what has no spelling
+func transliterate(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < unicode.MaxASCII:
+			b.WriteRune(r)
+		case transliterations[r] != "":
+			b.WriteString(transliterations[r])
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
+
+// sanitizeFolderName makes a name usable as a folder on Windows, SMB shares
+// and exFAT: forbidden characters are replaced (":" and "/" become "-"),
+// trailing dots and spaces removed, reserved device names escaped and the
+// length capped. Blank names stay blank. asciiOnly also transliterates
+// accents and Greek letters.
+func sanitizeFolderName(name string, asciiOnly bool) string {
+	if strings.TrimSpace(name) == "" {
+		return ""
+	}
+	if asciiOnly {
+		name = transliterate(name)
+	}
+	// "Sh2-155: Cave Nebula" -> "Sh2-155 - Cave Nebula"
+	name = strings.ReplaceAll(name, ": ", " - ")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 32 || r == 0x7f:
+			return -1
+		case strings.ContainsRune(`:/\|`, r):
+			return '-'
+		case r == '"':
+			return '\''
+		case strings.ContainsRune(`<>?*`, r):
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+
+	// Cap the length in UTF-16 units, the unit NTFS and exFAT count in
+	if units := utf16.Encode([]rune(name)); len(units) > maxNameUnits {
+		runes := []rune(name)
+		for len(utf16.Encode(runes)) > maxNameUnits {
+			runes = runes[:len(runes)-1]
+		}
+		name = string(runes)
+	}
+	name = strings.TrimRight(name, ". ")
+
+	stem := strings.ToUpper(strings.TrimSpace(strings.SplitN(name, ".", 2)[0]))
+	if windowsReservedNames[stem] {
+		name = "_" + name
+	}
+	if name == "" {
+		// Nothing usable was left of a non-empty name
+		return "_"
+	}
+	return name
+}
+
+// safeFolderPart makes a header value or profile name (filter, camera...) usable as a folder name
+func safeFolderPart(name string) string {
+	return sanitizeFolderName(name, false)
+}
+
+// pathUnits returns the length of a path as Windows counts it (UTF-16 units)
+// and whether a session folder that long leaves too little room for the frame
+// names below it under the Windows path limit
+func pathUnits(path string) (int, bool) {
+	units := len(utf16.Encode([]rune(path)))
+	return units, units+fileNameReserve > maxPathUnits
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestSanitizeFolderName(t *testing.T) {
+	tests := []struct {
+		name      string
+		asciiOnly bool
+		want      string
+	}{
+		{"Sh2-155: Cave Nebula", false, "Sh2-155 - Cave Nebula"},
+		{"NGC 7000/IC 5070", false, "NGC 7000-IC 5070"},
+		{`M42 "Orion"`, false, "M42 'Orion'"},
+		{"What? <Nebula>*", false, "What Nebula"},
+		{"Bad\tname\x7f", false, "Badname"},
+		{"Veil  Nebula. . ", false, "Veil Nebula"},
+		{"CON", false, "_CON"},
+		{"aux.txt", false, "_aux.txt"},
+		{"COM1 ", false, "_COM1"},
+		{"Console", false, "Console"},
+		{"   ", false, ""},
+		{"???", false, "_"},
+		{"Ñandú", false, "Ñandú"},
+		{"Ñandú", true, "Nandu"},
+		{"η Carinae", true, "eta Carinae"},
+		{"Cœur de l’Éléphant", true, "Coeur de l'Elephant"},
+		{"M42 ★", true, "M42"},
+		{strings.Repeat("a", 300), false, strings.Repeat("a", maxNameUnits)},
+		{strings.Repeat("🌌", 200), false, strings.Repeat("🌌", maxNameUnits/2)},
+	}
+	for _, tt := range tests {
+		got := sanitizeFolderName(tt.name, tt.asciiOnly)
+		if got != tt.want {
+			t.Errorf("sanitizeFolderName(%q, %v) = %q, want %q", tt.name, tt.asciiOnly, got, tt.want)
+		}
+		if n := len(utf16.Encode([]rune(got))); n > maxNameUnits {
+			t.Errorf("sanitizeFolderName(%q) is %d UTF-16 units long", tt.name, n)
+		}
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Barnard's Loop", "Barnard's Loop"},
+		{"Ångström Łódź", "Angstrom Lodz"},
+		{"α\u00a0Cen", "alpha Cen"},
+		{"10×50 – 2°", "10x50 - 2deg"},
+		{"Straße", "Strasse"},
+		{"Ω Centauri", " Centauri"},
+	}
+	for _, tt := range tests {
+		if got := transliterate(tt.name); got != tt.want {
+			t.Errorf("transliterate(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File written into every Night_DD capture folder describing how it was created
+const sessionMetadataFile = "session.json"
+
+// sessionMetadata is the content of session.json
+type sessionMetadata struct {
+	ToolVersion string            `json:"tool_version"`
+	Created     time.Time         `json:"created"`
+	Updated     time.Time         `json:"updated"`
+	Target      sessionTarget     `json:"target"`
+	Date        sessionDate       `json:"date"`
+	Equipment   *equipmentProfile `json:"equipment,omitempty"`
+	Site        *siteProfile      `json:"site,omitempty"`
+	Panel       string            `json:"panel,omitempty"` // mosaic panel id ("02", "01-02")
+	Files       []movedFile       `json:"files,omitempty"`
+}
+
+// sessionTarget records how the target folder name was resolved
+type sessionTarget struct {
+	Input   string         `json:"input"`
+	Folder  string         `json:"folder"`
+	Objects []targetObject `json:"objects,omitempty"`
+}
+
+// sessionDate records the raw date input and the folders it produced
+type sessionDate struct {
+	Input string `json:"input"`
+	Year  string `json:"year"`
+	Month string `json:"month"`
+	Day   string `json:"day"`
+}
+
+// movedFile is a single file moved into the session
+type movedFile struct {
+	Path    string    `json:"path"` // relative to the capture folder
+	Source  string    `json:"source"`
+	Bytes   int64     `json:"bytes"`
+	SHA256  string    `json:"sha256,omitempty"` // files copied from another disk
+	MovedAt time.Time `json:"moved_at"`
+}
+
+// loadSessionMetadata reads session.json from a capture path, returning nil if it doesn't exist
+func loadSessionMetadata(capturePath string) (*sessionMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(capturePath, sessionMetadataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var meta sessionMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// openSessionMetadata loads the existing sidecar of a capture path or starts a new one
+func openSessionMetadata(capturePath string) *sessionMetadata {
+	meta, err := loadSessionMetadata(capturePath)
+	if err != nil || meta == nil {
+		meta = &sessionMetadata{Created: time.Now()}
+	}
+	return meta
+}
+
+// addFiles appends moved files, storing their paths relative to the capture folder
+func (s *sessionMetadata) addFiles(capturePath string, files []movedFile) {
+	for _, f := range files {
+		if rel, err := filepath.Rel(capturePath, f.Path); err == nil {
+			f.Path = filepath.ToSlash(rel)
+		}
+		s.Files = append(s.Files, f)
+	}
+}
+
+// save writes session.json into the capture path
+func (s *sessionMetadata) save(capturePath string) error {
+	s.ToolVersion = toolVersion
+	s.Updated = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(capturePath, sessionMetadataFile), data, 0644)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Optional user configuration, read from the same folder where the sessions are generated
+const userConfigFile = "astrosession.json"
+
+// userConfig is the content of astrosession.json
+type userConfig struct {
+	Language         string             `json:"language,omitempty"`
+	FolderLanguage   string             `json:"folder_language,omitempty"`
+	CaptureLayout    string             `json:"capture_layout,omitempty"`
+	MonthFolders     string             `json:"month_folders,omitempty"`
+	CaptureFolders   []string           `json:"capture_folders,omitempty"`
+	PerFilterFolders bool               `json:"per_filter_folders,omitempty"`
+	DefaultEquipment string             `json:"default_equipment,omitempty"`
+	Equipment        []equipmentProfile `json:"equipment,omitempty"`
+	DefaultSite      string             `json:"default_site,omitempty"`
+	Sites            []siteProfile      `json:"sites,omitempty"`
+	Calibration      calibrationConfig  `json:"calibration,omitempty"`
+	Flats            flatsConfig        `json:"flats,omitempty"`
+	FieldNaming      fieldNamingConfig  `json:"field_naming,omitempty"`
+	FilenamePatterns []string           `json:"filename_patterns,omitempty"`
+	Cull             cullConfig         `json:"cull,omitempty"`
+	LogRules         []logRule          `json:"log_rules,omitempty"`
+}
+
+// equipmentProfile describes one imaging rig
+type equipmentProfile struct {
+	Name        string   `json:"name"`
+	Telescope   string   `json:"telescope,omitempty"`
+	FocalLength float64  `json:"focal_length_mm,omitempty"`
+	Aperture    float64  `json:"aperture_mm,omitempty"`
+	Camera      string   `json:"camera,omitempty"`
+	PixelSize   float64  `json:"pixel_size_um,omitempty"`
+	Mount       string   `json:"mount,omitempty"`
+	Filters     []string `json:"filters,omitempty"`
+
+	// Guide scope and camera, used to convert guiding errors to arcsec
+	GuideFocalLength float64 `json:"guide_focal_length_mm,omitempty"`
+	GuidePixelSize   float64 `json:"guide_pixel_size_um,omitempty"`
+
+	// Values written by the capture software in TELESCOP/INSTRUME, when they
+	// differ from Telescope/Camera
+	FITSTelescope  string `json:"fits_telescope,omitempty"`
+	FITSInstrument string `json:"fits_instrument,omitempty"`
+}
+
+// loadUserConfig reads astrosession.json from baseDir, returning the defaults when it doesn't exist
+func loadUserConfig(baseDir string) (userConfig, error) {
+	cfg := userConfig{}
+	data, err := os.ReadFile(filepath.Join(baseDir, userConfigFile))
+	if err != nil && !os.IsNotExist(err) {
+		return cfg, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return userConfig{}, err
+		}
+	}
+	cfg.applyDefaults()
+	return cfg, nil
+}
+
+// applyDefaults fills the settings the user left out
+func (c *userConfig) applyDefaults() {
+	if c.CaptureLayout == "" {
+		c.CaptureLayout = defaultCaptureLayout
+	}
+	if len(c.CaptureFolders) == 0 {
+		c.CaptureFolders = captureSubfolders
+	}
+	if c.Calibration.TempTolerance == 0 {
+		c.Calibration.TempTolerance = defaultTempTolerance
+	}
+	if c.Calibration.ExposureTolerance == 0 {
+		c.Calibration.ExposureTolerance = defaultExposureTolerance
+	}
+	if c.Flats.RotatorTolerance == 0 {
+		c.Flats.RotatorTolerance = defaultRotatorTolerance
+	}
+	if c.Flats.FocuserTolerance == 0 {
+		c.Flats.FocuserTolerance = defaultFocuserTolerance
+	}
+}
+
+// loadUserConfigOrDefault loads astrosession.json, warning and falling back to the defaults on errors
+func loadUserConfigOrDefault(baseDir string) userConfig {
+	cfg, err := loadUserConfig(baseDir)
+	if err != nil {
+		trPrintf("⚠️  Could not read %s, using defaults: %v\n", userConfigFile, err)
+		cfg = userConfig{}
+		cfg.applyDefaults()
+	}
+	applyLanguage(cfg)
+	// Warnings come after applyLanguage so they follow the configured language
+	if !validMonthFolders(cfg.MonthFolders) {
+		trPrintf("⚠️  Unknown month_folders '%s' in %s (use %s, %s or %s); the %s style is used.\n",
+			cfg.MonthFolders, userConfigFile, monthFoldersName, monthFoldersNumberName, monthFoldersNumber, monthFoldersName)
+	}
+	applyFilenamePatterns(cfg)
+	return cfg
+}
+
+// findEquipment looks up a profile by name (case-insensitive)
+func (c userConfig) findEquipment(name string) *equipmentProfile {
+	for i := range c.Equipment {
+		if strings.EqualFold(c.Equipment[i].Name, name) {
+			return &c.Equipment[i]
+		}
+	}
+	return nil
+}
+
+// detectEquipment picks the profile whose telescope/camera match the TELESCOP
+// and INSTRUME keywords of a frame
+func (c userConfig) detectEquipment(header fitsHeader) *equipmentProfile {
+	telescop := strings.ToLower(header.String("TELESCOP"))
+	instrume := strings.ToLower(header.String("INSTRUME"))
+
+	var best *equipmentProfile
+	bestScore := 0
+	for i := range c.Equipment {
+		p := &c.Equipment[i]
+		score := 0
+		if matchesHeaderValue(telescop, p.FITSTelescope, p.Telescope) {
+			score++
+		}
+		if matchesHeaderValue(instrume, p.FITSInstrument, p.Camera) {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
+
+// matchesHeaderValue compares a lowercase header value with the first non-empty candidate
+func matchesHeaderValue(value string, candidates ...string) bool {
+	if value == "" {
+		return false
+	}
+	for _, c := range candidates {
+		if c != "" {
+			c = strings.ToLower(c)
+			return strings.Contains(value, c) || strings.Contains(c, value)
+		}
+	}
+	return false
+}
+
+// pixelScale returns the image scale in arcsec/pixel, or 0 when unknown
+func (e equipmentProfile) pixelScale() float64 {
+	return arcsecPerPixel(e.PixelSize, e.FocalLength)
+}
+
+// guidePixelScale returns the guide camera scale in arcsec/pixel, or 0 when unknown
+func (e equipmentProfile) guidePixelScale() float64 {
+	return arcsecPerPixel(e.GuidePixelSize, e.GuideFocalLength)
+}
+
+func arcsecPerPixel(pixelSize, focalLength float64) float64 {
+	if focalLength <= 0 || pixelSize <= 0 {
+		return 0
+	}
+	return 206.265 * pixelSize / focalLength
+}
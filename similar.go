@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Minimum edit-distance similarity (0-1) for a folder to be suggested
+const minFolderSimilarity = 0.75
+
+// Maximum number of candidate folders shown to the user
+const maxFolderCandidates = 5
+
+var reDesignation = regexp.MustCompile(`(?i)(?:^|[^a-z])(M|NGC|IC)[ _]?(\d+)`)
+
+// folderCandidate is an existing target folder that may hold the same object
+type folderCandidate struct {
+	Name   string
+	Score  float64
+	Reason string
+}
+
+// findSimilarFolders ranks the existing folders in baseDir that look like the
+// target being created. keys are the raw names typed or resolved for the target
+// (input, final folder name, technical designations, aliases, common names).
+func findSimilarFolders(baseDir string, keys []string) []folderCandidate {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		return nil
+	}
+
+	var normKeys []string
+	keyDesignations := map[string]bool{}
+	for _, k := range keys {
+		if n := normalizeName(k); n != "" {
+			normKeys = append(normKeys, n)
+		}
+		for _, d := range extractDesignations(k) {
+			keyDesignations[d] = true
+		}
+	}
+
+	var candidates []folderCandidate
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") || e.Name() == rejectedFolder || e.Name() == calibrationFolder {
+			continue
+		}
+		if c, ok := scoreFolder(baseDir, e.Name(), normKeys, keyDesignations); ok {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	if len(candidates) > maxFolderCandidates {
+		candidates = candidates[:maxFolderCandidates]
+	}
+	return candidates
+}
+
+// scoreFolder compares a single folder name against the target keys
+func scoreFolder(baseDir, folder string, normKeys []string, keyDesignations map[string]bool) (folderCandidate, bool) {
+	normFolder := normalizeName(folder)
+	normTech := normalizeName(technicalPart(folder))
+	normCommon := normalizeName(strings.TrimPrefix(folder, technicalPart(folder)))
+
+	for _, k := range normKeys {
+		if k == normFolder || k == normTech {
+			return folderCandidate{Name: folder, Score: 1, Reason: "same name"}, true
+		}
+	}
+
+	// target.json knows every alias Sesame returned when the folder was created
+	if meta, err := loadTargetMetadata(filepath.Join(baseDir, folder)); err == nil && meta != nil {
+		for _, obj := range meta.Objects {
+			for _, k := range normKeys {
+				if obj.hasAlias(k) {
+					return folderCandidate{Name: folder, Score: 0.95, Reason: "known alias of " + obj.Designation}, true
+				}
+			}
+		}
+	}
+
+	for _, d := range extractDesignations(folder) {
+		if keyDesignations[d] {
+			return folderCandidate{Name: folder, Score: 0.9, Reason: "shares designation " + strings.ToUpper(d)}, true
+		}
+	}
+
+	best := 0.0
+	for _, k := range normKeys {
+		for _, f := range []string{normFolder, normTech, normCommon} {
+			if f == "" {
+				continue
+			}
+			if s := similarity(k, f); s > best {
+				best = s
+			}
+		}
+	}
+	if best >= minFolderSimilarity {
+		// Keep fuzzy hits below designation hits
+		return folderCandidate{Name: folder, Score: best * 0.85, Reason: "similar spelling"}, true
+	}
+	return folderCandidate{}, false
+}
+
+// technicalPart strips the "(Common Name)" suffix from a folder name
+func technicalPart(folder string) string {
+	if idx := strings.Index(folder, " ("); idx > 0 {
+		return folder[:idx]
+	}
+	return folder
+}
+
+// extractDesignations returns the normalized M/NGC/IC designations found in s (e.g. "ngc7000")
+func extractDesignations(s string) []string {
+	var out []string
+	for _, m := range reDesignation.FindAllStringSubmatch(s, -1) {
+		out = append(out, strings.ToLower(m[1])+m[2])
+	}
+	return out
+}
+
+// similarity returns 1 - levenshtein(a, b) / max(len(a), len(b))
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindSimilarFolders(t *testing.T) {
+	tests := []struct {
+		name    string
+		folders []string
+		keys    []string
+		want    []string // "folder | reason | score"
+	}{
+		{
+			name:    "same designation with other spacing",
+			folders: []string{"NGC_7000 (North America Nebula)", "NGC_7023 (Iris Nebula)"},
+			keys:    []string{"NGC7000"},
+			want:    []string{"NGC_7000 (North America Nebula) | same name | 1.00"},
+		},
+		{
+			name:    "designation inside a field folder",
+			folders: []string{"M81_M82 (Bode's & Cigar Galaxies)", "M101 (Pinwheel Galaxy)"},
+			keys:    []string{"M 82"},
+			want:    []string{"M81_M82 (Bode's & Cigar Galaxies) | shares designation M82 | 0.90"},
+		},
+		{
+			name:    "M1 is not M101",
+			folders: []string{"M101 (Pinwheel Galaxy)"},
+			keys:    []string{"M1", "Crab Nebula"},
+		},
+		{
+			name:    "misspelled common name ranks below designations",
+			folders: []string{"Andromedda Galaxy", "M31 (Andromeda Galaxy)", "Orion"},
+			keys:    []string{"M 31", "Andromeda Galaxy"},
+			want: []string{
+				"M31 (Andromeda Galaxy) | same name | 1.00",
+				"Andromedda Galaxy | similar spelling | 0.80",
+			},
+		},
+		{
+			name:    "rejected, calibration and hidden folders are ignored",
+			folders: []string{rejectedFolder, calibrationFolder, ".M42"},
+			keys:    []string{"M42", "Rejected", "Calibration"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			for _, f := range tt.folders {
+				if err := os.Mkdir(filepath.Join(baseDir, f), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []string
+			for _, c := range findSimilarFolders(baseDir, tt.keys) {
+				got = append(got, fmt.Sprintf("%s | %s | %.2f", c.Name, c.Reason, c.Score))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSimilarFoldersByAlias(t *testing.T) {
+	baseDir := t.TempDir()
+	root := filepath.Join(baseDir, "Elephant Trunk")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	obj := targetObject{Designation: "IC_1396", Aliases: []string{"IC 1396", "Elephant's Trunk Nebula"}}
+	if err := saveTargetMetadata(root, []targetObject{obj}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := findSimilarFolders(baseDir, []string{"IC1396"})
+	if len(got) != 1 || got[0].Reason != "known alias of IC_1396" || got[0].Score != 0.95 {
+		t.Errorf("got %+v, want the folder found through target.json", got)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"time"
+	_ "time/tzdata" // IANA zones must resolve on Windows too
+)
+
+// Hour before which a local time still belongs to the previous night
+const nightRolloverHour = 12
+
+// siteProfile describes an observing location
+type siteProfile struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Elevation float64 `json:"elevation_m,omitempty"`
+	Timezone  string  `json:"timezone,omitempty"` // IANA name, e.g. "America/Denver"
+	Bortle    int     `json:"bortle,omitempty"`
+}
+
+// findSite looks up a site profile by name (case-insensitive)
+func (c userConfig) findSite(name string) *siteProfile {
+	for i := range c.Sites {
+		if strings.EqualFold(c.Sites[i].Name, name) {
+			return &c.Sites[i]
+		}
+	}
+	return nil
+}
+
+// location returns the time zone of the site, falling back to the machine's zone
+func (s *siteProfile) location() *time.Location {
+	if s == nil || s.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// nightDate returns the calendar date of the night t belongs to in loc: anything
+// before noon is still part of the previous evening's session
+func nightDate(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	if local.Hour() < nightRolloverHour {
+		local = local.AddDate(0, 0, -1)
+	}
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// File stored at the root of every target folder describing its identity
+const targetMetadataFile = "target.json"
+
+// targetMetadata is the content of target.json
+type targetMetadata struct {
+	Folder  string         `json:"folder"`
+	Objects []targetObject `json:"objects"`
+	Created time.Time      `json:"created"`
+}
+
+// targetObject is one resolved object inside the target folder (a field may hold several)
+type targetObject struct {
+	Designation string   `json:"designation"`
+	CommonName  string   `json:"common_name,omitempty"`
+	ObjectType  string   `json:"object_type,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	RA          *float64 `json:"ra_deg,omitempty"`
+	Dec         *float64 `json:"dec_deg,omitempty"`
+}
+
+// newTargetObject builds the metadata entry for a target from its Sesame resolution
+func newTargetObject(designation string, res sesameResult) targetObject {
+	obj := targetObject{
+		Designation: designation,
+		CommonName:  res.CommonName,
+		Aliases:     res.Aliases,
+	}
+	if res.Found {
+		obj.ObjectType = res.ObjectType
+	}
+	if res.HasCoords {
+		ra, dec := res.RA, res.Dec
+		obj.RA, obj.Dec = &ra, &dec
+	}
+	return obj
+}
+
+// loadTargetMetadata reads target.json from a target root, returning nil if it doesn't exist
+func loadTargetMetadata(targetRoot string) (*targetMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(targetRoot, targetMetadataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var meta targetMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// saveTargetMetadata writes target.json, merging aliases and keeping the original
+// creation date when the folder already has one. An unreadable target.json is left
+// untouched so that its aliases can be fixed by hand.
+func saveTargetMetadata(targetRoot string, objects []targetObject) error {
+	meta, err := loadTargetMetadata(targetRoot)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		meta = &targetMetadata{Created: time.Now()}
+	}
+	meta.Folder = filepath.Base(targetRoot)
+
+	for _, obj := range objects {
+		merged := false
+		for i := range meta.Objects {
+			existing := &meta.Objects[i]
+			if normalizeName(existing.Designation) != normalizeName(obj.Designation) && !existing.hasAlias(obj.Designation) {
+				continue
+			}
+			existing.Aliases = appendUnique(existing.Aliases, obj.Aliases...)
+			if existing.CommonName == "" {
+				existing.CommonName = obj.CommonName
+			}
+			if existing.ObjectType == "" {
+				existing.ObjectType = obj.ObjectType
+			}
+			if existing.RA == nil {
+				existing.RA, existing.Dec = obj.RA, obj.Dec
+			}
+			merged = true
+			break
+		}
+		if !merged {
+			meta.Objects = append(meta.Objects, obj)
+		}
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(targetRoot, targetMetadataFile), data, 0644)
+}
+
+// hasAlias reports whether name matches the designation or one of the aliases
+func (o targetObject) hasAlias(name string) bool {
+	norm := normalizeName(name)
+	if norm == "" {
+		return false
+	}
+	if normalizeName(o.Designation) == norm {
+		return true
+	}
+	for _, a := range o.Aliases {
+		if normalizeName(a) == norm {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appendUnique appends the values not already present in list
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		exists := false
+		for _, l := range list {
+			if l == v {
+				exists = true
+				break
+			}
+		}
+		if !exists && v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// prepareTargetRoot creates the processing folders of a target root and merges
+// the objects into its target.json
+func prepareTargetRoot(targetRoot string, objects []targetObject) error {
+	for _, folder := range processingSubfolders {
+		if err := os.MkdirAll(filepath.Join(targetRoot, filepath.FromSlash(folder)), 0755); err != nil {
+			return err
+		}
+	}
+	if err := saveTargetMetadata(targetRoot, objects); err != nil {
+		trPrintf("⚠️  Could not write %s: %v\n", targetMetadataFile, err)
+	}
+	return nil
+}
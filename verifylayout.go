@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func init() {
+	registerCommand("verify-layout", "verify-layout [-fix]",
+		"Check that every capture session has a Rejected mirror with the same frame folders, and report orphan mirrors (-fix creates the missing folders).",
+		runVerifyLayoutCommand)
+}
+
+func runVerifyLayoutCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("verify-layout", flag.ExitOnError)
+	fix := fs.Bool("fix", false, "create the missing mirror folders")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		return errors.New("usage: " + commands["verify-layout"].usage)
+	}
+
+	sessions := findSessionFolders(baseDir, false)
+	if len(sessions) == 0 {
+		return fmt.Errorf("no capture sessions found in %s", baseDir)
+	}
+
+	problems := 0
+	for _, sessionPath := range sessions {
+		mirror, err := rejectedMirrorPath(baseDir, sessionPath)
+		if err != nil {
+			continue
+		}
+		rel, _ := filepath.Rel(baseDir, sessionPath)
+
+		// Folders the layout expects, plus any frame folder already in the session
+		var equipment *equipmentProfile
+		if meta, err := loadSessionMetadata(sessionPath); err == nil && meta != nil {
+			equipment = meta.Equipment
+		}
+		var expected []string
+		for _, folder := range cfg.rejectedFolders(equipment) {
+			if info, err := os.Stat(filepath.Join(sessionPath, filepath.FromSlash(folder))); err == nil && info.IsDir() {
+				expected = appendUnique(expected, folder)
+			}
+		}
+		for _, folder := range captureFrameFolders(sessionPath) {
+			expected = appendUnique(expected, folder)
+		}
+
+		var missing []string
+		for _, folder := range expected {
+			if info, err := os.Stat(filepath.Join(mirror, filepath.FromSlash(folder))); err != nil || !info.IsDir() {
+				missing = append(missing, folder)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		problems++
+		if _, err := os.Stat(mirror); err != nil {
+			trPrintf("❌ %s: no %s mirror\n", filepath.ToSlash(rel), rejectedFolder)
+		} else {
+			trPrintf("❌ %s: mirror lacks %s\n", filepath.ToSlash(rel), strings.Join(missing, ", "))
+		}
+		if *fix {
+			for _, folder := range missing {
+				if err := os.MkdirAll(filepath.Join(mirror, filepath.FromSlash(folder)), 0755); err != nil {
+					return err
+				}
+			}
+			trPrintf("   -> created %d folders in %s\n", len(missing), mirror)
+		}
+	}
+
+	// Mirrors whose capture session no longer exists (renamed or deleted)
+	rejectedRoot := filepath.Join(baseDir, rejectedFolder)
+	for _, mirror := range findSessionFolders(rejectedRoot, true) {
+		rel, _ := filepath.Rel(rejectedRoot, mirror)
+		if _, err := os.Stat(filepath.Join(baseDir, rel)); os.IsNotExist(err) {
+			problems++
+			trPrintf("⚠️  %s/%s: orphan mirror, no capture session at %s\n", rejectedFolder, filepath.ToSlash(rel), filepath.ToSlash(rel))
+		}
+	}
+
+	if problems == 0 {
+		trPrintf("✅ %d sessions checked, every %s mirror follows the capture layout.\n", len(sessions), rejectedFolder)
+	} else {
+		trPrintf("\n%d problems found in %d sessions.\n", problems, len(sessions))
+	}
+	return nil
+}
+
+// findSessionFolders returns the session folders below root: folders with a
+// session.json or a Lights folder (in the Rejected mirror, also a Flats folder
+// or a rejection log). The Rejected and Calibration trees are skipped.
+func findSessionFolders(root string, mirror bool) []string {
+	var sessions []string
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root {
+			if strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if rel, _ := filepath.Rel(root, path); !mirror && (rel == rejectedFolder || rel == calibrationFolder) {
+				return filepath.SkipDir
+			}
+		}
+		markers := []string{sessionMetadataFile, "Lights"}
+		if mirror {
+			markers = append(markers, "Flats", rejectionLogFile)
+		}
+		for _, m := range markers {
+			if _, err := os.Stat(filepath.Join(path, m)); err == nil && path != root {
+				sessions = append(sessions, path)
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	sort.Strings(sessions)
+	return sessions
+}
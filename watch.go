@@ -0,0 +1,480 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How often the polling watcher rescans the source folder
+const defaultWatchInterval = 2 * time.Second
+
+func init() {
+	registerCommand("watch", "watch -source <capture folder> [-poll] [-interval 2s] [-equipment name] [-site name]",
+		"Watch the capture program's output folder during the night and file every finished light/flat into its Target/Night_ session, resolved from the OBJECT header.",
+		runWatchCommand)
+}
+
+// watchSession is a session the watcher has already filed frames into
+type watchSession struct {
+	capturePath string
+	label       string // target/session path shown in messages
+	perFilter   bool
+	meta        *sessionMetadata
+}
+
+// watchState holds what the watcher filed so far
+type watchState struct {
+	baseDir   string
+	cfg       userConfig
+	equipment *equipmentProfile // forced with -equipment, otherwise detected per frame
+	site      *siteProfile
+
+	targets  map[string]resolvedTarget // by lowercase OBJECT, without panel suffix
+	sessions map[string]*watchSession  // by capture path
+	last     *watchSession             // session of the latest light, for flats without OBJECT
+	pending  []string                  // flats waiting for a light to know their session
+
+	filed, skipped int
+	bytes          int64
+	lastFile       string
+	lastAt         time.Time
+}
+
+func runWatchCommand(args []string) error {
+	baseDir, cfg, err := commandBaseDir()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	source := fs.String("source", "", "folder where the capture program saves the frames")
+	poll := fs.Bool("poll", false, "scan the folder periodically instead of using file system events (network shares)")
+	interval := fs.Duration("interval", defaultWatchInterval, "scan interval for -poll")
+	equipmentName := fs.String("equipment", "", "equipment profile name from "+userConfigFile)
+	siteName := fs.String("site", cfg.DefaultSite, "observing site name from "+userConfigFile)
+	fs.Parse(args)
+	if *source == "" || fs.NArg() != 0 {
+		return errors.New("usage: " + commands["watch"].usage)
+	}
+
+	src, err := filepath.Abs(cleanPath(*source))
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return fmt.Errorf("'%s' is not a folder", src)
+	}
+	// Filing frames inside the watched folder would feed them back to the watcher
+	if rel, err := filepath.Rel(src, baseDir); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("the archive %s can't be inside the watched folder", baseDir)
+	}
+
+	state := &watchState{
+		baseDir:  baseDir,
+		cfg:      cfg,
+		site:     cfg.findSite(*siteName),
+		targets:  map[string]resolvedTarget{},
+		sessions: map[string]*watchSession{},
+	}
+	if *equipmentName != "" {
+		if state.equipment = cfg.findEquipment(*equipmentName); state.equipment == nil {
+			return fmt.Errorf("equipment profile '%s' not found in %s", *equipmentName, userConfigFile)
+		}
+	}
+
+	found := make(chan string, 64)
+	watchErr := make(chan error, 1)
+	go func() {
+		if *poll {
+			watchErr <- pollFiles(src, *interval, found)
+		} else {
+			watchErr <- watchFiles(src, found)
+		}
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	trPrintf("👀 Watching %s (Ctrl+C to stop)\n", src)
+	start := time.Now()
+	for {
+		select {
+		case path := <-found:
+			state.file(path)
+		case err := <-watchErr:
+			fmt.Println()
+			return err
+		case <-interrupt:
+			trPrintf("\n\n✅ %d frames filed (%s) in %s, %d skipped.\n", state.filed, formatBytes(state.bytes), time.Since(start).Round(time.Second), state.skipped)
+			if len(state.pending) > 0 {
+				trPrintf("⚠️  %d flats were left in %s: no light told which session they belong to.\n", len(state.pending), src)
+			}
+			return nil
+		case <-ticker.C:
+			state.printStatus()
+		}
+	}
+}
+
+// printStatus redraws the status line, like printProgressBar does for moves
+func (w *watchState) printStatus() {
+	idle := tr("waiting for the first frame")
+	if !w.lastAt.IsZero() {
+		idle = tr("last %s, %s ago", w.lastFile, time.Since(w.lastAt).Round(time.Second))
+	}
+	trPrintf("\r👀 Filed: %d (%s) | Skipped: %d | %-60s", w.filed, formatBytes(w.bytes), w.skipped, idle)
+}
+
+// file moves a finished frame into its session
+func (w *watchState) file(path string) {
+	if !isFrameFile(path) {
+		return
+	}
+	header, err := readFrameHeader(path)
+	if err != nil {
+		w.skip(path, tr("unreadable header: %v", err))
+		return
+	}
+
+	var folder string
+	switch imageType(header) {
+	case frameLight, "":
+		folder = "Lights"
+	case frameFlat:
+		folder = "Flats"
+	default:
+		w.skip(path, tr("darks and bias go to the library with 'calibration ingest'"))
+		return
+	}
+
+	object := header.String("OBJECT")
+	if folder == "Flats" && isPlaceholderObject(object) {
+		if w.last == nil {
+			w.pending = append(w.pending, path)
+			trPrintf("\r⏳ %s waits for a light to know its session%-30s\n", filepath.Base(path), "")
+			return
+		}
+		w.moveInto(path, w.last, folder)
+		return
+	}
+	if isPlaceholderObject(object) {
+		w.skip(path, tr("no OBJECT keyword"))
+		return
+	}
+
+	session, err := w.session(object, header, path)
+	if err != nil {
+		w.skip(path, err.Error())
+		return
+	}
+	w.moveInto(path, session, folder)
+
+	if folder == "Lights" {
+		w.last = session
+		for _, flat := range w.pending {
+			w.moveInto(flat, session, "Flats")
+		}
+		w.pending = nil
+	}
+}
+
+// session returns (creating it the first time) the session a frame belongs to
+func (w *watchState) session(object string, header fitsHeader, path string) (*watchSession, error) {
+	// Mosaic panels ("Veil Panel 2") share the target of their base name
+	name, panel, _ := parsePanelObject(object)
+	key := strings.ToLower(name)
+	target, ok := w.targets[key]
+	if !ok {
+		trPrintf("\r🔭 New target '%s', resolving...%-40s\n", name, "")
+		target = resolveTargetAuto(w.baseDir, name, framePosition(header, object), w.cfg.FieldNaming)
+		w.targets[key] = target
+		if target.Reused {
+			trPrintf("-> Using existing folder '%s'\n", target.Folder)
+		} else {
+			trPrintf("-> Folder '%s'\n", target.Folder)
+		}
+	}
+
+	night, err := frameNight(header, path, w.site)
+	if err != nil {
+		return nil, err
+	}
+
+	equipment := w.equipment
+	if equipment == nil {
+		equipment = w.cfg.detectEquipment(header)
+	}
+	if equipment == nil {
+		equipment = w.cfg.findEquipment(w.cfg.DefaultEquipment)
+	}
+
+	tokens := w.cfg.nightTokens(night, equipment, w.site)
+	sessionRelPath := expandLayout(w.cfg.CaptureLayout, tokens)
+	targetRoot := filepath.Join(w.baseDir, target.Folder)
+	capturePath, rejectedBase := sessionPaths(w.baseDir, target.Folder, panel, sessionRelPath)
+	if s, ok := w.sessions[capturePath]; ok {
+		return s, nil
+	}
+
+	if err := prepareTargetRoot(targetRoot, target.Objects); err != nil {
+		return nil, err
+	}
+	if err := w.cfg.createSessionTree(capturePath, rejectedBase, equipment); err != nil {
+		return nil, err
+	}
+
+	meta := openSessionMetadata(capturePath)
+	meta.Target = sessionTarget{Input: object, Folder: target.Folder, Objects: target.Objects}
+	meta.Date = sessionDate{Input: header.String("DATE-OBS"), Year: tokens.Year, Month: tokens.Month, Day: tokens.Day}
+	if equipment != nil {
+		meta.Equipment = equipment
+	}
+	if w.site != nil {
+		meta.Site = w.site
+	}
+	meta.Panel = panel
+
+	s := &watchSession{
+		capturePath: capturePath,
+		label:       filepath.ToSlash(filepath.Join(target.Folder, panelFolder(panel), sessionRelPath)),
+		perFilter:   w.cfg.PerFilterFolders,
+		meta:        meta,
+	}
+	w.sessions[capturePath] = s
+	trPrintf("📁 Session: %s\n", s.label)
+	return s, nil
+}
+
+// moveInto moves a frame into a capture folder of the session and records it in session.json
+func (w *watchState) moveInto(path string, s *watchSession, folder string) {
+	var moved moveLog
+	var movedBytes int64
+	destDir := frameDestDir(path, filepath.Join(s.capturePath, folder), s.perFilter && isFilterSplitFolder(folder))
+	if !moveOneFile(path, destDir, &movedBytes, &moved) {
+		w.skipped++
+		return
+	}
+
+	s.meta.addFiles(s.capturePath, moved.files)
+	if err := s.meta.save(s.capturePath); err != nil {
+		trPrintf("\r⚠️  Could not update %s: %v\n", sessionMetadataFile, err)
+	}
+	// Per-filter folders appear as frames arrive; keep the Rejected mirror in step
+	if mirror, err := rejectedMirrorPath(w.baseDir, s.capturePath); err == nil {
+		syncMirrorFolders(s.capturePath, mirror)
+	}
+
+	w.filed++
+	w.bytes += movedBytes
+	w.lastFile = filepath.Base(path)
+	w.lastAt = time.Now()
+	rel, _ := filepath.Rel(s.capturePath, destDir)
+	fmt.Printf("\r✅ %s -> %s/%s%-20s\n", filepath.Base(path), s.label, filepath.ToSlash(rel), "")
+}
+
+func (w *watchState) skip(path, reason string) {
+	w.skipped++
+	trPrintf("\r⏭️  %s skipped: %s%-20s\n", filepath.Base(path), reason, "")
+}
+
+// formatBytes prints a size with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// fileTracker remembers the size and modification time of the files seen in
+// the watched folder, so a frame is reported once and only after it stopped growing
+type fileTracker map[string]*trackedFile
+
+type trackedFile struct {
+	size     int64
+	modTime  time.Time
+	reported bool
+}
+
+// check records the current state of a file and tells if it should be reported:
+// its size and modification time didn't change since the previous check. Files
+// seen at startup are taken as already reported.
+func (t fileTracker) check(path string, info os.FileInfo, initial bool) bool {
+	prev, ok := t[path]
+	switch {
+	case !ok:
+		t[path] = &trackedFile{size: info.Size(), modTime: info.ModTime(), reported: initial}
+	case prev.size != info.Size() || !prev.modTime.Equal(info.ModTime()):
+		prev.size, prev.modTime, prev.reported = info.Size(), info.ModTime(), false
+	case !prev.reported && info.Size() > 0:
+		// Same size and time as one interval ago: the writer is done
+		prev.reported = true
+		return true
+	}
+	return false
+}
+
+// walkWatched calls fn for every visible file and folder under root
+func walkWatched(root string, fn func(path string, d os.DirEntry)) {
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		fn(path, d)
+		return nil
+	})
+}
+
+// pollFiles scans root every interval and reports files whose size and
+// modification time didn't change since the previous scan. Files present at
+// startup are not reported.
+func pollFiles(root string, interval time.Duration, found chan<- string) error {
+	known := fileTracker{}
+	scan := func(initial bool) {
+		seen := map[string]bool{}
+		walkWatched(root, func(path string, d os.DirEntry) {
+			if d.IsDir() {
+				return
+			}
+			info, err := d.Info()
+			if err != nil {
+				return
+			}
+			seen[path] = true
+			if known.check(path, info, initial) {
+				found <- path
+			}
+		})
+		// Forget files that were moved away
+		for path := range known {
+			if !seen[path] {
+				delete(known, path)
+			}
+		}
+	}
+
+	if _, err := os.Stat(root); err != nil {
+		return err
+	}
+	scan(true)
+	for range time.Tick(interval) {
+		scan(false)
+	}
+	return nil
+}
+
+// watchFiles reports the files written or moved under root using file system
+// events (inotify, ReadDirectoryChangesW, kqueue). Events only tell which files
+// changed: each one is reported after the same size-stable check as pollFiles,
+// since close-write isn't available on every system. New subfolders are
+// watched as they appear, and the whole tree is rescanned when the system
+// dropped events during a burst.
+func watchFiles(root string, found chan<- string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	known := fileTracker{}
+	changed := map[string]bool{} // files to check on the next tick
+	addTree := func(dir string, initial bool) {
+		walkWatched(dir, func(path string, d os.DirEntry) {
+			if d.IsDir() {
+				watcher.Add(path)
+				return
+			}
+			if initial {
+				if info, err := d.Info(); err == nil {
+					known.check(path, info, true)
+				}
+				return
+			}
+			changed[path] = true
+		})
+	}
+
+	if _, err := os.Stat(root); err != nil {
+		return err
+	}
+	addTree(root, true)
+	ticker := time.NewTicker(defaultWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if strings.HasPrefix(filepath.Base(event.Name), ".") {
+				continue
+			}
+			switch {
+			case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+				// Moved away (usually by us): a new file with this name is a new frame
+				watcher.Remove(event.Name)
+				for path := range known {
+					if path == event.Name || strings.HasPrefix(path, event.Name+string(filepath.Separator)) {
+						delete(known, path)
+						delete(changed, path)
+					}
+				}
+			case event.Has(fsnotify.Create):
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// Files written into the folder before its watch existed
+					addTree(event.Name, false)
+				} else {
+					changed[event.Name] = true
+				}
+			case event.Has(fsnotify.Write):
+				changed[event.Name] = true
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return err
+			}
+			trPrintf("\r⚠️  Too many file events at once, rescanning %s%-20s\n", root, "")
+			addTree(root, false)
+		case <-ticker.C:
+			for path := range changed {
+				info, err := os.Stat(path)
+				if err != nil || info.IsDir() {
+					delete(changed, path)
+					delete(known, path)
+					continue
+				}
+				if known.check(path, info, false) {
+					delete(changed, path)
+					found <- path
+				} else if known[path].reported {
+					// Unchanged and already reported (startup files after a rescan)
+					delete(changed, path)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// XISF files start with a signature and the length of their XML header
+const (
+	xisfSignature    = "XISF0100"
+	xisfMaxHeaderLen = 16 << 20
+)
+
+// XISF properties and the FITS keywords they stand in for when an image has no
+// FITSKeyword of its own. scale converts the XISF unit (meters) to the FITS one (mm).
+var xisfProperties = map[string]struct {
+	keyword string
+	scale   float64
+}{
+	"Observation:Object:Name":             {"OBJECT", 0},
+	"Observation:Object:RA":               {"RA", 0},
+	"Observation:Object:Dec":              {"DEC", 0},
+	"Observation:Time:Start":              {"DATE-OBS", 0},
+	"Instrument:ExposureTime":             {"EXPTIME", 0},
+	"Instrument:Filter:Name":              {"FILTER", 0},
+	"Instrument:Camera:Name":              {"INSTRUME", 0},
+	"Instrument:Camera:Gain":              {"EGAIN", 0}, // e-/ADU, not the gain setting
+	"Instrument:Camera:XBinning":          {"XBINNING", 0},
+	"Instrument:Sensor:Temperature":       {"CCD-TEMP", 0},
+	"Instrument:Sensor:TargetTemperature": {"SET-TEMP", 0},
+	"Instrument:Telescope:Name":           {"TELESCOP", 0},
+	"Instrument:Telescope:FocalLength":    {"FOCALLEN", 1000},
+	"Instrument:Telescope:Aperture":       {"APTDIA", 1000},
+}
+
+// xisfProperty is a Property element; strings may be in the element text instead of value
+type xisfProperty struct {
+	ID    string `xml:"id,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"`
+}
+
+// xisfDocument is the part of the XML header the tool reads
+type xisfDocument struct {
+	Images []struct {
+		Keywords []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"FITSKeyword"`
+		Properties []xisfProperty `xml:"Property"`
+	} `xml:"Image"`
+	Metadata   []xisfProperty `xml:"Metadata>Property"` // file-level properties
+	Properties []xisfProperty `xml:"Property"`          // global properties outside Metadata
+}
+
+// isXISFFile reports whether the file name has the XISF extension
+func isXISFFile(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".xisf")
+}
+
+// readXISFHeader reads the FITS keywords of the first image of an XISF file,
+// completed with its XISF properties (and the file's)
+func readXISFHeader(path string) (fitsHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var start [16]byte
+	if _, err := io.ReadFull(f, start[:]); err != nil || string(start[:8]) != xisfSignature {
+		return nil, errors.New("not an XISF file")
+	}
+	length := binary.LittleEndian.Uint32(start[8:12])
+	if length == 0 || length > xisfMaxHeaderLen {
+		return nil, fmt.Errorf("corrupt XISF header length %d", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, fmt.Errorf("truncated XISF header: %w", err)
+	}
+
+	var doc xisfDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("XISF header: %v", err)
+	}
+	if len(doc.Images) == 0 {
+		return nil, errors.New("XISF file without images")
+	}
+
+	image := doc.Images[0]
+	header := fitsHeader{}
+	for _, k := range image.Keywords {
+		key := strings.ToUpper(strings.TrimSpace(k.Name))
+		if _, exists := header[key]; !exists && key != "" {
+			header[key] = parseFITSValue(k.Value)
+		}
+	}
+	properties := append(append(image.Properties, doc.Metadata...), doc.Properties...)
+	for _, p := range properties {
+		target, ok := xisfProperties[p.ID]
+		if !ok || header.String(target.keyword) != "" {
+			continue
+		}
+		value := strings.TrimSpace(p.Value)
+		if value == "" {
+			value = strings.TrimSpace(p.Text)
+		}
+		if target.scale != 0 {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			value = strconv.FormatFloat(v*target.scale, 'f', -1, 64)
+		}
+		if value != "" {
+			header[target.keyword] = value
+		}
+	}
+	return header, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// xisfFile encodes an XISF file with the given XML header and no data blocks
+func xisfFile(header string) []byte {
+	data := binary.LittleEndian.AppendUint32([]byte(xisfSignature), uint32(len(header)))
+	return append(append(data, 0, 0, 0, 0), header...)
+}
+
+func TestReadXISFHeader(t *testing.T) {
+	const open = `<?xml version="1.0" encoding="UTF-8"?><xisf version="1.0" xmlns="http://www.pixinsight.com/xisf">`
+	tests := []struct {
+		name    string
+		data    []byte
+		want    fitsHeader
+		wantErr string
+	}{
+		{
+			name: "keywords win over properties",
+			data: xisfFile(open + `<Image geometry="4:4:1" sampleFormat="UInt16" location="attachment:4096:32">
+				<FITSKeyword name="OBJECT" value="'M31     '" comment=""/>
+				<FITSKeyword name="exptime" value="300." comment=""/>
+				<FITSKeyword name="OBJECT" value="'M33'" comment="second one is ignored"/>
+				<Property id="Observation:Object:Name" type="String">Other</Property>
+				<Property id="Instrument:Filter:Name" type="String" value="Ha"/>
+				<Property id="Instrument:Telescope:FocalLength" type="Float32" value="0.53"/>
+				<Property id="PCL:Unknown" type="String" value="x"/>
+			</Image></xisf>`),
+			want: fitsHeader{"OBJECT": "M31", "EXPTIME": "300.", "FILTER": "Ha", "FOCALLEN": "530"},
+		},
+		{
+			name: "image, then Metadata, then root properties",
+			data: xisfFile(open + `<Metadata>
+				<Property id="Instrument:Camera:Name" type="String">Metadata camera</Property>
+				<Property id="Observation:Time:Start" type="TimePoint" value="2024-03-01T22:15:30Z"/>
+			</Metadata>
+			<Property id="Instrument:Camera:Name" type="String">Root camera</Property>
+			<Property id="Instrument:Telescope:Name" type="String">Root scope</Property>
+			<Property id="Instrument:Telescope:Aperture" type="Float32" value="not a number"/>
+			<Image geometry="4:4:1" sampleFormat="UInt16" location="attachment:4096:32">
+				<Property id="Instrument:ExposureTime" type="Float32" value="120"/>
+			</Image></xisf>`),
+			want: fitsHeader{"INSTRUME": "Metadata camera", "DATE-OBS": "2024-03-01T22:15:30Z", "TELESCOP": "Root scope", "EXPTIME": "120"},
+		},
+		{
+			name: "first image only",
+			data: xisfFile(open + `<Image><FITSKeyword name="FILTER" value="'L'"/></Image>
+				<Image><FITSKeyword name="FILTER" value="'R'"/><FITSKeyword name="GAIN" value="100"/></Image></xisf>`),
+			want: fitsHeader{"FILTER": "L"},
+		},
+
+		{name: "fits file", data: []byte(strings.Repeat("SIMPLE  =                    T", 3)), wantErr: "not an XISF file"},
+		{name: "zero length", data: xisfFile(""), wantErr: "corrupt XISF header length 0"},
+		{name: "truncated", data: xisfFile(open + "<Image/></xisf>")[:40], wantErr: "truncated XISF header"},
+		{name: "no image", data: xisfFile(open + `<Metadata/></xisf>`), wantErr: "XISF file without images"},
+		{name: "broken xml", data: xisfFile(open + `<Image>`), wantErr: "XISF header"},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i))+".xisf")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readXISFHeader(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}